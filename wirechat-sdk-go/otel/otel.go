@@ -0,0 +1,90 @@
+// Package wirechatotel adapts OpenTelemetry to the wirechat trace.Tracer interface.
+// It lives in its own module so the core SDK does not depend on OpenTelemetry.
+package wirechatotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+)
+
+// instrumentationName identifies spans created by the SDK.
+const instrumentationName = "github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go"
+
+// Tracer implements trace.Tracer on top of an OpenTelemetry tracer.
+type Tracer struct {
+	tracer     oteltrace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ trace.Tracer = (*Tracer)(nil)
+
+// NewTracer creates a tracer from the global OpenTelemetry provider and propagator.
+func NewTracer() *Tracer {
+	return NewTracerWith(otel.GetTracerProvider(), otel.GetTextMapPropagator())
+}
+
+// NewTracerWith creates a tracer from an explicit provider and propagator.
+func NewTracerWith(provider oteltrace.TracerProvider, propagator propagation.TextMapPropagator) *Tracer {
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagator,
+	}
+}
+
+// Start implements trace.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, name, oteltrace.WithAttributes(convert(attrs)...))
+	return ctx, spanAdapter{span: span}
+}
+
+// Inject implements trace.Tracer.
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type spanAdapter struct {
+	span oteltrace.Span
+}
+
+func (s spanAdapter) SetAttributes(attrs ...trace.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s spanAdapter) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s spanAdapter) End() {
+	s.span.End()
+}
+
+func convert(attrs []trace.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,124 @@
+package wirechatotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+)
+
+func newRecorder() (*Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return NewTracerWith(provider, propagation.TraceContext{}), exporter
+}
+
+func TestTracerRecordsSpans(t *testing.T) {
+	tracer, exporter := newRecorder()
+
+	ctx, span := tracer.Start(context.Background(), "wirechat.send",
+		trace.String(trace.AttrRoom, "general"),
+		trace.Int(trace.AttrAttempt, 2),
+		trace.Attribute{Key: "big", Value: int64(1) << 40},
+		trace.Attribute{Key: "ok", Value: true},
+		trace.Attribute{Key: "ratio", Value: 0.5},
+		trace.Attribute{Key: "other", Value: []int{1}},
+	)
+	span.SetAttributes(trace.String(trace.AttrErrorCode, "rate_limited"))
+	span.RecordError(errors.New("boom"))
+
+	header := http.Header{}
+	tracer.Inject(ctx, header)
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if got.Name != "wirechat.send" {
+		t.Fatalf("unexpected span name %q", got.Name)
+	}
+	want := map[attribute.Key]attribute.Value{
+		trace.AttrRoom:      attribute.StringValue("general"),
+		trace.AttrAttempt:   attribute.IntValue(2),
+		"big":               attribute.Int64Value(1 << 40),
+		"ok":                attribute.BoolValue(true),
+		"ratio":             attribute.Float64Value(0.5),
+		"other":             attribute.StringValue("[1]"),
+		trace.AttrErrorCode: attribute.StringValue("rate_limited"),
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range got.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, attrs[k].Emit(), v.Emit())
+		}
+	}
+	if got.Status.Code != codes.Error || got.Status.Description != "boom" {
+		t.Fatalf("unexpected status %+v", got.Status)
+	}
+	if len(got.Events) != 1 || got.Events[0].Name != "exception" {
+		t.Fatalf("expected an exception event, got %+v", got.Events)
+	}
+
+	// Inject propagates the active span
+	sc := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header))
+	if remote := oteltrace.SpanContextFromContext(sc); remote.TraceID() != got.SpanContext.TraceID() || remote.SpanID() != got.SpanContext.SpanID() {
+		t.Fatalf("traceparent %q does not match the span", header.Get("Traceparent"))
+	}
+}
+
+func TestTracerRESTSpans(t *testing.T) {
+	tracer, exporter := newRecorder()
+
+	traceparents := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("Traceparent")
+		http.Error(w, `{"error":"down"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := wirechat.DefaultConfig()
+	cfg.RESTBaseURL = srv.URL
+	cfg.Tracer = tracer
+	c := wirechat.NewClient(&cfg)
+	if _, err := c.REST.ListRooms(context.Background()); err == nil {
+		t.Fatalf("expected error from failing server")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if got.Name != "wirechat.rest /rooms" || got.Status.Code != codes.Error {
+		t.Fatalf("unexpected span %q with status %+v", got.Name, got.Status)
+	}
+	var status int64
+	for _, kv := range got.Attributes {
+		if kv.Key == trace.AttrStatusCode {
+			status = kv.Value.AsInt64()
+		}
+	}
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected status code attribute 500, got %d", status)
+	}
+	want := "00-" + got.SpanContext.TraceID().String() + "-" + got.SpanContext.SpanID().String() + "-01"
+	if tp := <-traceparents; tp != want {
+		t.Fatalf("traceparent = %q, want %q", tp, want)
+	}
+}
@@ -0,0 +1,213 @@
+package wirechat
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker guarding dials and REST calls.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all calls through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails calls fast with ErrorCircuitOpen until the cool-down expires.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe through to test whether the server recovered.
+	BreakerHalfOpen
+)
+
+// String returns the string representation of a BreakerState.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerEvent describes a circuit breaker state change.
+type BreakerEvent struct {
+	OldState BreakerState
+	NewState BreakerState
+	Failures int           // Consecutive failures that led to the change
+	Cooldown time.Duration // Time until the next probe when NewState is BreakerOpen
+}
+
+// breaker is a circuit breaker shared by WebSocket dials and rest.Client calls.
+// A nil breaker allows everything.
+type breaker struct {
+	threshold   int
+	cooldown    time.Duration
+	maxCooldown time.Duration
+	onChange    func(BreakerEvent)
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	current   time.Duration // Cool-down of the current open period; doubles on failed probes
+	openUntil time.Time
+	probeAt   time.Time // When the half-open probe was let through
+}
+
+// newBreaker returns nil when the breaker is disabled.
+func newBreaker(cfg *Config, onChange func(BreakerEvent)) *breaker {
+	if cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+	b := &breaker{
+		threshold:   cfg.BreakerThreshold,
+		cooldown:    cfg.BreakerCooldown,
+		maxCooldown: cfg.BreakerMaxCooldown,
+		onChange:    onChange,
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = 30 * time.Second
+	}
+	if b.maxCooldown < b.cooldown {
+		b.maxCooldown = b.cooldown
+	}
+	return b
+}
+
+// Allow reports whether a call may proceed. It returns an ErrorCircuitOpen
+// error while the breaker is open or a half-open probe is in flight.
+func (b *breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	var ev *BreakerEvent
+	switch b.state {
+	case BreakerOpen:
+		if now.Before(b.openUntil) {
+			b.mu.Unlock()
+			return NewError(ErrorCircuitOpen, "circuit breaker open")
+		}
+		ev = b.transitionLocked(BreakerHalfOpen)
+		b.probeAt = now
+	case BreakerHalfOpen:
+		// A probe that never reported back (e.g. cancelled) is replaced after a cool-down
+		if now.Sub(b.probeAt) < b.current {
+			b.mu.Unlock()
+			return NewError(ErrorCircuitOpen, "circuit breaker half-open, probe in flight")
+		}
+		b.probeAt = now
+	}
+	b.mu.Unlock()
+	b.fire(ev)
+	return nil
+}
+
+// Success records a successful call and closes the breaker.
+func (b *breaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.failures = 0
+	var ev *BreakerEvent
+	if b.state != BreakerClosed {
+		b.current = 0
+		ev = b.transitionLocked(BreakerClosed)
+	}
+	b.mu.Unlock()
+	b.fire(ev)
+}
+
+// Failure records a failed call. The breaker opens after threshold
+// consecutive failures or when a half-open probe fails.
+func (b *breaker) Failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.failures++
+	var ev *BreakerEvent
+	switch b.state {
+	case BreakerClosed:
+		if b.failures >= b.threshold {
+			b.current = b.cooldown
+			ev = b.openLocked()
+		}
+	case BreakerHalfOpen:
+		b.current = min(b.current*2, b.maxCooldown)
+		ev = b.openLocked()
+	}
+	b.mu.Unlock()
+	b.fire(ev)
+}
+
+// State returns the current breaker state.
+func (b *breaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// wait returns how long until the breaker lets the next call through.
+func (b *breaker) wait() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen {
+		return 0
+	}
+	return max(time.Until(b.openUntil), 0)
+}
+
+func (b *breaker) openLocked() *BreakerEvent {
+	b.openUntil = time.Now().Add(b.current)
+	return b.transitionLocked(BreakerOpen)
+}
+
+func (b *breaker) transitionLocked(state BreakerState) *BreakerEvent {
+	ev := &BreakerEvent{OldState: b.state, NewState: state, Failures: b.failures}
+	if state == BreakerOpen {
+		ev.Cooldown = b.current
+	}
+	b.state = state
+	return ev
+}
+
+// fire reports a transition outside of the lock.
+func (b *breaker) fire(ev *BreakerEvent) {
+	if ev != nil && b.onChange != nil {
+		b.onChange(*ev)
+	}
+}
+
+// breakerChanged logs and dispatches breaker transitions.
+func (c *Client) breakerChanged(ev BreakerEvent) {
+	fields := map[string]any{
+		"old_state": ev.OldState.String(),
+		"new_state": ev.NewState.String(),
+		"failures":  ev.Failures,
+	}
+	if ev.NewState == BreakerOpen {
+		fields["cooldown"] = ev.Cooldown.String()
+		c.logger.Warn("circuit breaker opened", fields)
+	} else {
+		c.logger.Info("circuit breaker state changed", fields)
+	}
+	c.dispatcher.fireBreakerChange(ev)
+}
+
+// BreakerState returns the state of the circuit breaker.
+// It is always BreakerClosed when the breaker is disabled.
+func (c *Client) BreakerState() BreakerState {
+	return c.breaker.State()
+}
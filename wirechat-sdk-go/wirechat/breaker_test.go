@@ -0,0 +1,66 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	cfg := DefaultConfig()
+	cfg.URL = "ws" + strings.TrimPrefix(dead.URL, "http")
+	cfg.RESTBaseURL = dead.URL + "/api"
+	cfg.Transport = transport.WebSocket{}
+	cfg.BreakerThreshold = 2
+	cfg.BreakerCooldown = 20 * time.Millisecond
+	c := NewClient(&cfg)
+
+	var events []BreakerEvent
+	c.OnBreakerStateChanged(func(ev BreakerEvent) { events = append(events, ev) })
+
+	ctx := context.Background()
+	for range 2 {
+		if err := c.Connect(ctx); !IsConnectionError(err) {
+			t.Fatalf("expected connection error, got %v", err)
+		}
+	}
+	if c.BreakerState() != BreakerOpen {
+		t.Fatalf("expected open breaker, got %s", c.BreakerState())
+	}
+
+	// Both WS dials and REST calls fail fast while open
+	circuitOpen := NewError(ErrorCircuitOpen, "")
+	if err := c.Connect(ctx); !errors.Is(err, circuitOpen) {
+		t.Fatalf("expected circuit_open from Connect, got %v", err)
+	}
+	if _, err := c.REST.ListRooms(ctx); !errors.Is(err, circuitOpen) {
+		t.Fatalf("expected circuit_open from REST, got %v", err)
+	}
+
+	// After the cool-down a failed probe reopens the breaker with a longer cool-down
+	time.Sleep(cfg.BreakerCooldown)
+	if err := c.Connect(ctx); !IsConnectionError(err) {
+		t.Fatalf("expected probe to dial, got %v", err)
+	}
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for i, ev := range events {
+		if ev.NewState != want[i] {
+			t.Fatalf("event %d: got %s, want %s", i, ev.NewState, want[i])
+		}
+	}
+	if events[2].Cooldown != 2*cfg.BreakerCooldown {
+		t.Fatalf("expected doubled cool-down, got %s", events[2].Cooldown)
+	}
+}
@@ -0,0 +1,75 @@
+package wirechat
+
+import (
+	"context"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// ChatClient is the public surface of Client used by application code.
+// Accept it instead of *Client so business logic can be unit-tested with
+// the recording fake in the fake package.
+type ChatClient interface {
+	Connect(ctx context.Context) error
+	Close() error
+	State() ConnectionState
+
+	UpdateConfig(fn func(*Config)) error
+
+	Join(ctx context.Context, room string) error
+	Leave(ctx context.Context, room string) error
+	Send(ctx context.Context, room, text string) error
+	SendWithDelivery(ctx context.Context, room, text string) (*Delivery, error)
+
+	// Rooms given by REST ID
+	JoinID(ctx context.Context, id int64) error
+	LeaveID(ctx context.Context, id int64) error
+	SendID(ctx context.Context, id int64, text string) error
+	SendWithDeliveryID(ctx context.Context, id int64, text string) (*Delivery, error)
+
+	OnMessage(fn func(MessageEvent))
+	OnUserJoined(fn func(UserEvent))
+	OnUserLeft(fn func(UserEvent))
+	OnHistory(fn func(HistoryEvent))
+	OnError(fn func(error))
+	OnStateChanged(fn func(StateEvent))
+	OnHeartbeat(fn func(HeartbeatEvent))
+	OnBreakerStateChanged(fn func(BreakerEvent))
+	OnRawFrame(fn func(Direction, []byte))
+	OnUnknownEvent(fn func(Outbound))
+
+	// Latency returns the last heartbeat round-trip time (0 if unknown).
+	Latency() time.Duration
+
+	// Custom events and commands, used by RegisterEvent and SendCommand
+	EventHandler
+	CommandSender
+
+	// RESTAPI returns the REST operations, or nil if no REST base URL is configured.
+	RESTAPI() rest.API
+}
+
+// EventHandler registers untyped handlers for custom server events.
+// RegisterEvent builds typed handlers on top of it.
+type EventHandler interface {
+	// HandleEvent registers fn for event. An error returned by fn is reported
+	// to OnError as ErrorSerialization.
+	HandleEvent(event string, fn func(Outbound) error)
+}
+
+// CommandSender sends frames the SDK does not model. SendCommand uses it.
+type CommandSender interface {
+	// SendFrame sends a frame of frameType with payload encoded as its data.
+	SendFrame(ctx context.Context, frameType string, payload any) error
+}
+
+var _ ChatClient = (*Client)(nil)
+
+// RESTAPI returns REST as a rest.API, or nil if no REST base URL is configured.
+func (c *Client) RESTAPI() rest.API {
+	if c.REST.BaseURL() == "" {
+		return nil
+	}
+	return c.REST
+}
@@ -2,35 +2,66 @@ package wirechat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
 	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/internal"
 	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
-
-	"github.com/coder/websocket"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
 )
 
+// errReconnectExhausted is returned by reconnect once MaxReconnectTries is exceeded.
+var errReconnectExhausted = NewError(ErrorDisconnected, "max reconnect attempts exceeded")
+
 // Client provides high-level SDK for WireChat.
 type Client struct {
-	cfg        Config
+	cfg        atomic.Pointer[Config] // Replaced as a whole by UpdateConfig
 	logger     Logger
+	metrics    Metrics
+	tracer     trace.Tracer
 	conn       *internal.Conn
-	rawConn    *websocket.Conn
-	writeCh    chan Inbound
+	queue      *writeQueue
 	dispatcher Dispatcher
-
-	// REST API client
+	resend     *resender
+	endpoints  *endpointPool
+	breaker    *breaker   // Shared by dials and REST calls; nil when disabled
+	roster     *roster    // Members of joined rooms; nil when disabled
+	feed       *storeFeed // Feeds Config.MessageStore; nil when not configured
+	rooms      *roomResolver
+
+	// REST API client. NewClient always sets it and it is never reassigned:
+	// failover and UpdateConfig change its base URL and token in place. Until a
+	// REST base URL is configured its requests fail with rest.ErrNoBaseURL.
 	REST *rest.Client
 
+	updateMu sync.Mutex // Serializes UpdateConfig calls
+
 	mu               sync.Mutex
 	state            ConnectionState
+	stateSince       time.Time // When the current state was entered
+	transportName    string    // Transport that established the current connection
+	endpoint         Endpoint  // Endpoint of the current connection
 	connected        bool
+	closed           bool // Set by Close, cleared by Connect
 	cancel           context.CancelFunc
+	readDone         chan struct{} // Closed when the read loop of the current run exits
+	writeCancel      context.CancelFunc
+	writeDone        chan struct{}   // Closed when the write loop of the current connection exits
 	joinedRooms      map[string]bool // Track joined rooms for auto-reconnect
 	reconnectAttempt int             // Current reconnection attempt count
-	messageBuffer    []Inbound       // Buffer for outgoing messages during disconnect
+	messageBuffer    []outgoing      // Buffer for outgoing messages during disconnect
+	connectedAt      time.Time       // When the current connection was established
+
+	outgoing []OutgoingInterceptor // Run on frames before they are written
+	incoming []IncomingInterceptor // Run on frames before they are dispatched
+
+	latency atomic.Int64 // Last heartbeat round-trip time in nanoseconds
 }
 
 // NewClient constructs a client with provided config.
@@ -38,30 +69,87 @@ type Client struct {
 // Set timeout to 0 to disable it.
 func NewClient(cfg *Config) *Client {
 	c := &Client{
-		cfg:         *cfg,
 		logger:      noopLogger{},
-		writeCh:     make(chan Inbound, 16),
+		queue:       newWriteQueue(cfg),
+		resend:      newResender(),
+		endpoints:   newEndpointPool(cfg),
 		state:       StateDisconnected,
+		stateSince:  time.Now(),
 		joinedRooms: make(map[string]bool),
 	}
 
-	// Initialize REST client if RESTBaseURL is provided
-	if cfg.RESTBaseURL != "" {
-		c.REST = rest.NewClient(cfg.RESTBaseURL)
-		if cfg.Token != "" {
-			c.REST.SetToken(cfg.Token)
-		}
+	c.metrics = cfg.Metrics
+	if c.metrics == nil {
+		c.metrics = noopMetrics{}
+	}
+	c.dispatcher.metrics = c.metrics
+	c.dispatcher.logger = c.logger
+
+	c.tracer = cfg.Tracer
+	if c.tracer == nil {
+		c.tracer = trace.Noop{}
+	}
+
+	own := *cfg
+	normalizeConfig(&own)
+	c.cfg.Store(&own)
+
+	c.breaker = newBreaker(cfg, c.breakerChanged)
+	c.rooms = newRoomResolver(c)
+	c.roster = newRoster(cfg, c.dispatcher.fireRosterChange)
+	if c.roster != nil {
+		c.dispatcher.addHooks(c.roster.hooks())
+	}
+	if cfg.MessageStore != nil {
+		c.feed = &storeFeed{c: c, store: cfg.MessageStore}
+		c.dispatcher.addHooks(c.feed.hooks())
 	}
 
+	// The REST client exists even without a base URL, so UpdateConfig can configure it later
+	c.REST = c.newREST(own.restBaseURL(), cfg.Token)
+
 	return c
 }
 
+// normalizeConfig fills in the defaults the client relies on being set.
+func normalizeConfig(cfg *Config) {
+	if cfg.Codec == nil {
+		cfg.Codec = codec.JSON
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = defaultTransport()
+	}
+}
+
+// config returns the current configuration. The returned value must not be modified.
+func (c *Client) config() *Config {
+	return c.cfg.Load()
+}
+
+// newREST creates the REST client wired to the client's observability and breaker.
+func (c *Client) newREST(baseURL, token string) *rest.Client {
+	r := rest.NewClient(baseURL)
+	if token != "" {
+		r.SetToken(token)
+	}
+	r.SetMetrics(c.metrics)
+	r.SetTracer(c.tracer)
+	r.SetLogger(c.logger)
+	if c.breaker != nil {
+		r.SetBreaker(c.breaker)
+	}
+	r.SetObserver(restObserver{c: c})
+	return r
+}
+
 // SetLogger overrides logger (optional).
 func (c *Client) SetLogger(l Logger) {
 	if l == nil {
 		return
 	}
 	c.logger = l
+	c.dispatcher.logger = l
+	c.REST.SetLogger(l)
 }
 
 // OnMessage registers callback for message events.
@@ -82,6 +170,22 @@ func (c *Client) OnError(fn func(error)) { c.dispatcher.SetOnError(fn) }
 // OnStateChanged registers callback for connection state changes.
 func (c *Client) OnStateChanged(fn func(StateEvent)) { c.dispatcher.SetOnStateChanged(fn) }
 
+// OnBreakerStateChanged registers callback for circuit breaker state changes.
+func (c *Client) OnBreakerStateChanged(fn func(BreakerEvent)) {
+	c.dispatcher.SetOnBreakerStateChanged(fn)
+}
+
+// OnHeartbeat registers callback for heartbeat results (latency or missed pings).
+func (c *Client) OnHeartbeat(fn func(HeartbeatEvent)) { c.dispatcher.SetOnHeartbeat(fn) }
+
+// OnRawFrame registers callback receiving the raw bytes of every frame on the wire.
+// The callback runs on the read or write loop and must not retain data.
+func (c *Client) OnRawFrame(fn func(Direction, []byte)) { c.dispatcher.SetOnRawFrame(fn) }
+
+// OnUnknownEvent registers callback for events the SDK does not recognize.
+// It fires before any decoding, so Outbound.Data holds the original payload.
+func (c *Client) OnUnknownEvent(fn func(Outbound)) { c.dispatcher.SetOnUnknownEvent(fn) }
+
 // State returns the current connection state.
 func (c *Client) State() ConnectionState {
 	c.mu.Lock()
@@ -94,54 +198,95 @@ func (c *Client) setState(newState ConnectionState, err error) {
 	c.mu.Lock()
 	oldState := c.state
 	c.state = newState
+	now := time.Now()
+	spent := now.Sub(c.stateSince)
+	c.stateSince = now
+	transportName := c.transportName
+	endpoint := c.endpoint.URL
 	c.mu.Unlock()
 
+	c.metrics.StateDuration(oldState, spent)
+
+	fields := map[string]any{
+		"old_state": oldState.String(),
+		"new_state": newState.String(),
+		"transport": transportName,
+		"endpoint":  endpoint,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	c.logger.Debug("state changed", fields)
+
 	// Fire callback outside of lock to avoid deadlocks
-	c.dispatcher.fireStateChange(oldState, newState, err)
+	c.dispatcher.fireStateChange(StateEvent{
+		OldState:  oldState,
+		NewState:  newState,
+		Transport: transportName,
+		Endpoint:  endpoint,
+		Error:     err,
+	})
 }
 
 // Connect dials the server, sends hello, and starts internal loops.
-func (c *Client) Connect(ctx context.Context) error {
+func (c *Client) Connect(ctx context.Context) (err error) {
+	ctx, span := c.tracer.Start(ctx, "wirechat.connect")
+	defer func() {
+		if err != nil {
+			c.logger.Error("connect failed", map[string]any{"error": err.Error()})
+		}
+		endSpan(span, err)
+	}()
+
 	c.mu.Lock()
 	if c.connected {
 		c.mu.Unlock()
 		return NewError(ErrorInvalidConfig, "already connected")
 	}
+	c.closed = false
 	c.mu.Unlock()
 
 	c.setState(StateConnecting, nil)
 
-	if c.cfg.URL == "" {
-		err := NewError(ErrorInvalidConfig, "empty URL")
+	if err := c.config().Validate(); err != nil {
 		c.setState(StateError, err)
 		return err
 	}
-	u, err := url.Parse(c.cfg.URL)
-	if err != nil {
-		wrappedErr := WrapError(ErrorInvalidConfig, "invalid WebSocket URL", err)
-		c.setState(StateError, wrappedErr)
-		return wrappedErr
-	}
 
-	dialCtx := ctx
-	if c.cfg.HandshakeTimeout > 0 {
-		var cancel context.CancelFunc
-		dialCtx, cancel = context.WithTimeout(ctx, c.cfg.HandshakeTimeout)
-		defer cancel()
+	// Try each endpoint at most once, best first
+	var ep Endpoint
+	for range c.endpoints.len() {
+		ep, err = c.dialEndpoint(ctx)
+		if err == nil || ctx.Err() != nil || !IsConnectionError(err) {
+			break
+		}
+	}
+	if err == nil {
+		err = c.hello(ctx, ep)
 	}
-
-	ws, _, err := websocket.Dial(dialCtx, u.String(), nil)
 	if err != nil {
-		wrappedErr := WrapError(ErrorConnection, "failed to dial WebSocket", err)
-		c.setState(StateError, wrappedErr)
-		return wrappedErr
+		c.setState(StateError, err)
+		return err
 	}
 
-	c.rawConn = ws
-	c.conn = internal.NewConn(ws, c.cfg.ReadTimeout, c.cfg.WriteTimeout)
+	runCtx, cancel := context.WithCancel(context.Background())
+	if !c.start(runCtx, cancel) {
+		cancel()
+		_ = c.currentConn().Close()
+		return NewError(ErrorDisconnected, "client closed during connect")
+	}
+	c.setState(StateConnected, nil)
+	c.logger.Info("connected", map[string]any{"url": ep.URL})
+	return nil
+}
+
+// hello sends the handshake on a freshly dialed connection. A failure closes
+// the connection and counts against the endpoint and the breaker.
+func (c *Client) hello(ctx context.Context, ep Endpoint) error {
+	cfg := c.config()
 
 	// Use protocol from config, fallback to constant if not set
-	protocol := c.cfg.Protocol
+	protocol := cfg.Protocol
 	if protocol == 0 {
 		protocol = ProtocolVersion
 	}
@@ -150,34 +295,87 @@ func (c *Client) Connect(ctx context.Context) error {
 		Type: inboundHello,
 		Data: HelloPayload{
 			Protocol: protocol,
-			Token:    c.cfg.Token,
-			User:     c.cfg.User,
+			Token:    cfg.Token,
+			User:     cfg.User,
 		},
 	}
-	if err := c.conn.Write(ctx, hello); err != nil {
-		_ = c.conn.Close(websocket.StatusInternalError, "handshake error")
-		wrappedErr := WrapError(ErrorConnection, "failed to send hello handshake", err)
-		c.setState(StateError, wrappedErr)
-		return wrappedErr
+	if err := c.writeHello(ctx, hello); err != nil {
+		_ = c.conn.CloseNow()
+		c.endpoints.failure(ep)
+		c.breaker.Failure()
+		return WrapError(ErrorConnection, "failed to send hello handshake", err)
 	}
+	c.metrics.FrameSent(inboundHello)
+	return nil
+}
+
+// start marks the client connected and launches the loops for the current
+// connection under runCtx, which cancel stops. It starts nothing and returns
+// false when Close has been called in the meantime.
+func (c *Client) start(runCtx context.Context, cancel context.CancelFunc) bool {
+	done := make(chan struct{})
 
-	runCtx, cancel := context.WithCancel(context.Background())
-	c.cancel = cancel
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.cancel = cancel
+	c.readDone = done
 	c.connected = true
 	c.reconnectAttempt = 0 // Reset reconnect counter on successful connect
+	conn := c.conn
 	c.mu.Unlock()
 
-	c.setState(StateConnected, nil)
+	go c.readLoop(runCtx, done)
+	c.startWriter(runCtx)
+	c.startHeartbeat(runCtx, conn)
+	return true
+}
 
-	go c.readLoop(runCtx)
-	go c.writeLoop(runCtx)
-	return nil
+// startWriter starts the write loop for the current connection. The loop of
+// the previous connection is cancelled and must exit before the new one
+// starts, so the lanes are never drained by two loops at once.
+func (c *Client) startWriter(ctx context.Context) {
+	writeCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	c.mu.Lock()
+	prevCancel, prevDone := c.writeCancel, c.writeDone
+	c.writeCancel, c.writeDone = cancel, done
+	conn := c.conn
+	c.mu.Unlock()
+
+	if prevCancel != nil {
+		prevCancel()
+		<-prevDone
+	}
+	go func() {
+		defer close(done)
+		c.writeLoop(writeCtx, conn)
+	}()
+}
+
+// stopWriter cancels the write loop of the current connection without
+// waiting for it; startWriter waits before the next loop starts.
+func (c *Client) stopWriter() {
+	c.mu.Lock()
+	cancel := c.writeCancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
 }
 
 // Join subscribes to a room.
-func (c *Client) Join(ctx context.Context, room string) error {
+func (c *Client) Join(ctx context.Context, room string) (err error) {
+	ctx, span := c.startFrameSpan(ctx, "wirechat.join", inboundJoin, room)
+	defer func() { endSpan(span, err) }()
+
+	// Reset before sending so membership events for the join are not lost
+	c.roster.reset(room)
 	if err := c.send(ctx, Inbound{Type: inboundJoin, Data: JoinPayload{Room: room}}); err != nil {
+		c.roster.drop(room)
 		return err
 	}
 
@@ -190,7 +388,10 @@ func (c *Client) Join(ctx context.Context, room string) error {
 }
 
 // Leave unsubscribes from a room.
-func (c *Client) Leave(ctx context.Context, room string) error {
+func (c *Client) Leave(ctx context.Context, room string) (err error) {
+	ctx, span := c.startFrameSpan(ctx, "wirechat.leave", inboundLeave, room)
+	defer func() { endSpan(span, err) }()
+
 	if err := c.send(ctx, Inbound{Type: inboundLeave, Data: JoinPayload{Room: room}}); err != nil {
 		return err
 	}
@@ -199,46 +400,91 @@ func (c *Client) Leave(ctx context.Context, room string) error {
 	c.mu.Lock()
 	delete(c.joinedRooms, room)
 	c.mu.Unlock()
+	c.roster.drop(room)
 
 	return nil
 }
 
 // Send publishes a message to a room.
-func (c *Client) Send(ctx context.Context, room, text string) error {
+func (c *Client) Send(ctx context.Context, room, text string) (err error) {
+	ctx, span := c.startFrameSpan(ctx, "wirechat.send", inboundMsg, room)
+	defer func() { endSpan(span, err) }()
+
 	return c.send(ctx, Inbound{Type: inboundMsg, Data: MsgPayload{Room: room, Text: text}})
 }
 
-// Close shuts down client and closes WebSocket.
+// SendWithDelivery publishes a message to a room and returns a handle
+// reporting its delivery status (queued, pending, sent, confirmed or failed).
+func (c *Client) SendWithDelivery(ctx context.Context, room, text string) (_ *Delivery, err error) {
+	ctx, span := c.startFrameSpan(ctx, "wirechat.send", inboundMsg, room)
+	defer func() { endSpan(span, err) }()
+
+	// Queued, pending, sent, a pending/sent pair per resend, and the final status
+	d := newDelivery(room, text)
+	out := outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: room, Text: text}}, delivery: d}
+	if err := c.enqueue(ctx, out); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Close shuts down client and closes the connection. Messages still
+// buffered, queued or awaiting confirmation are failed with ErrorDisconnected.
 func (c *Client) Close() error {
 	c.mu.Lock()
+	c.closed = true
 	if c.cancel != nil {
 		c.cancel()
 	}
 	c.connected = false
+	buffered := c.messageBuffer
+	c.messageBuffer = nil
 	c.mu.Unlock()
 
 	c.setState(StateClosed, nil)
 
-	if c.conn != nil {
-		return c.conn.Close(websocket.StatusNormalClosure, "client close")
+	closedErr := NewError(ErrorDisconnected, "client closed")
+	for _, out := range append(buffered, c.queue.drain()...) {
+		out.delivery.fail(closedErr)
+	}
+	c.resetResend(closedErr)
+	c.metrics.BufferDepth(0)
+
+	if conn := c.currentConn(); conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
+// currentConn returns the connection installed by the last successful dial.
+func (c *Client) currentConn() *internal.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
 func (c *Client) send(ctx context.Context, in Inbound) error {
+	return c.enqueue(ctx, outgoing{in: in})
+}
+
+// enqueue puts a frame into the write queue, or into the message buffer while disconnected.
+func (c *Client) enqueue(ctx context.Context, out outgoing) error {
 	c.mu.Lock()
 	connected := c.connected
 
 	// If not connected and buffering is enabled, buffer the message
-	if !connected && c.cfg.BufferMessages {
+	if !connected && c.config().BufferMessages {
 		// Check buffer size limit
-		if len(c.messageBuffer) >= c.cfg.MaxBufferSize {
+		if len(c.messageBuffer) >= c.config().MaxBufferSize {
 			c.mu.Unlock()
 			return NewError(ErrorNotConnected, "message buffer full")
 		}
 		// Add to buffer
-		c.messageBuffer = append(c.messageBuffer, in)
+		c.messageBuffer = append(c.messageBuffer, out)
+		depth := len(c.messageBuffer)
 		c.mu.Unlock()
+		c.metrics.BufferDepth(depth)
+		out.delivery.setStatus(DeliveryQueued)
 		return nil
 	}
 	c.mu.Unlock()
@@ -247,17 +493,17 @@ func (c *Client) send(ctx context.Context, in Inbound) error {
 		return NewError(ErrorNotConnected, "client not connected")
 	}
 
-	select {
-	case c.writeCh <- in:
+	// Keep per-room order behind messages waiting for a rate limit resend
+	if c.config().ResendRateLimited && out.in.Type == inboundMsg && c.resend.hold(out) {
 		return nil
-	case <-ctx.Done():
-		return ctx.Err()
 	}
+
+	return c.push(ctx, out)
 }
 
 // reconnect attempts to reconnect with exponential backoff.
-func (c *Client) reconnect(ctx context.Context) error {
-	if !c.cfg.AutoReconnect {
+func (c *Client) reconnect(ctx context.Context) (err error) {
+	if !c.config().AutoReconnect {
 		return NewError(ErrorDisconnected, "auto-reconnect disabled")
 	}
 
@@ -267,27 +513,39 @@ func (c *Client) reconnect(ctx context.Context) error {
 	c.mu.Unlock()
 
 	// Check max tries
-	if c.cfg.MaxReconnectTries > 0 && attempt > c.cfg.MaxReconnectTries {
-		err := NewError(ErrorDisconnected, "max reconnect attempts exceeded")
-		c.setState(StateError, err)
-		return err
+	if c.config().MaxReconnectTries > 0 && attempt > c.config().MaxReconnectTries {
+		c.setState(StateError, errReconnectExhausted)
+		return errReconnectExhausted
 	}
 
+	start := time.Now()
+	c.metrics.ReconnectAttempt(attempt)
+	ctx, span := c.tracer.Start(ctx, "wirechat.reconnect", trace.Int(trace.AttrAttempt, attempt))
+	defer func() {
+		c.metrics.ReconnectDuration(time.Since(start), err == nil)
+		endSpan(span, err)
+	}()
+
 	// Calculate delay with exponential backoff: 1s, 2s, 4s, 8s, 16s, 30s (max)
 	var delay time.Duration
 	switch {
 	case attempt <= 0:
 		// Should never happen, but handle gracefully
-		delay = c.cfg.ReconnectInterval
+		delay = c.config().ReconnectInterval
 	case attempt <= 30:
 		// Safe: attempt > 0, so attempt-1 >= 0
-		delay = c.cfg.ReconnectInterval * (1 << (attempt - 1))
+		delay = c.config().ReconnectInterval * (1 << (attempt - 1))
 	default:
 		// Cap at 2^30 to prevent overflow
-		delay = c.cfg.ReconnectInterval * (1 << 30)
+		delay = c.config().ReconnectInterval * (1 << 30)
+	}
+	if delay > c.config().MaxReconnectDelay {
+		delay = c.config().MaxReconnectDelay
 	}
-	if delay > c.cfg.MaxReconnectDelay {
-		delay = c.cfg.MaxReconnectDelay
+
+	// An open breaker pushes the attempt back to its half-open probe
+	if wait := c.breaker.wait(); wait > delay {
+		delay = wait
 	}
 
 	c.logger.Warn("reconnecting after delay", map[string]interface{}{
@@ -305,68 +563,44 @@ func (c *Client) reconnect(ctx context.Context) error {
 	// Attempt reconnection
 	c.setState(StateReconnecting, nil)
 
-	// Parse URL
-	u, err := url.Parse(c.cfg.URL)
+	ep, err := c.dialEndpoint(ctx)
 	if err != nil {
-		return WrapError(ErrorInvalidConfig, "invalid WebSocket URL", err)
-	}
-
-	// Dial with handshake timeout
-	dialCtx := ctx
-	if c.cfg.HandshakeTimeout > 0 {
-		var cancel context.CancelFunc
-		dialCtx, cancel = context.WithTimeout(ctx, c.cfg.HandshakeTimeout)
-		defer cancel()
-	}
-
-	ws, _, err := websocket.Dial(dialCtx, u.String(), nil)
-	if err != nil {
-		return WrapError(ErrorConnection, "failed to dial WebSocket", err)
-	}
-
-	c.rawConn = ws
-	c.conn = internal.NewConn(ws, c.cfg.ReadTimeout, c.cfg.WriteTimeout)
-
-	// Send hello
-	protocol := c.cfg.Protocol
-	if protocol == 0 {
-		protocol = ProtocolVersion
-	}
-
-	hello := Inbound{
-		Type: inboundHello,
-		Data: HelloPayload{
-			Protocol: protocol,
-			Token:    c.cfg.Token,
-			User:     c.cfg.User,
-		},
+		return err
 	}
-	if err := c.conn.Write(ctx, hello); err != nil {
-		_ = c.conn.Close(websocket.StatusInternalError, "handshake error")
-		return WrapError(ErrorConnection, "failed to send hello handshake", err)
+	if err := c.hello(ctx, ep); err != nil {
+		return err
 	}
 
 	// Reconnection successful
 	c.mu.Lock()
 	c.connected = true
 	c.reconnectAttempt = 0 // Reset counter on success
+	conn := c.conn
 	c.mu.Unlock()
 
+	// Start writing before restoring, so rejoins and the buffer flush drain
+	c.startWriter(ctx)
+	c.startHeartbeat(ctx, conn)
 	c.setState(StateConnected, nil)
+	c.logger.Info("reconnected", map[string]any{"url": ep.URL, "attempt": attempt})
+
+	c.restore(ctx)
+	return nil
+}
 
+// restore re-joins rooms and replays buffered messages on a new connection.
+func (c *Client) restore(ctx context.Context) {
 	// Re-join all rooms
 	if err := c.rejoinRooms(ctx); err != nil {
 		c.logger.Warn("failed to rejoin some rooms", map[string]interface{}{"error": err.Error()})
 	}
 
 	// Flush buffered messages
-	if c.cfg.BufferMessages {
+	if c.config().BufferMessages {
 		if err := c.flushBuffer(ctx); err != nil {
 			c.logger.Warn("failed to flush message buffer", map[string]interface{}{"error": err.Error()})
 		}
 	}
-
-	return nil
 }
 
 // rejoinRooms re-joins all previously joined rooms after reconnection.
@@ -380,6 +614,7 @@ func (c *Client) rejoinRooms(ctx context.Context) error {
 
 	for _, room := range rooms {
 		// Send join without updating joinedRooms (already tracked)
+		c.roster.reset(room)
 		if err := c.send(ctx, Inbound{Type: inboundJoin, Data: JoinPayload{Room: room}}); err != nil {
 			return WrapError(ErrorConnection, "failed to rejoin room: "+room, err)
 		}
@@ -388,44 +623,154 @@ func (c *Client) rejoinRooms(ctx context.Context) error {
 	return nil
 }
 
-// flushBuffer sends all buffered messages after reconnection.
+// flushBuffer drains buffered messages into the write queue after reconnection.
+// Messages are moved in batches no larger than the queue capacity, waiting for
+// the write loop to make room instead of failing when the queue is full. The
+// flush stops when ctx is done or the write loop of the connection exits.
 func (c *Client) flushBuffer(ctx context.Context) error {
 	c.mu.Lock()
-	buffered := make([]Inbound, len(c.messageBuffer))
-	copy(buffered, c.messageBuffer)
-	c.messageBuffer = c.messageBuffer[:0] // Clear buffer
+	writeDone := c.writeDone
 	c.mu.Unlock()
 
-	for _, msg := range buffered {
-		// Send directly to writeCh (client is already connected)
-		select {
-		case c.writeCh <- msg:
-			// Message sent successfully
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// writeCh full, this shouldn't happen but handle gracefully
-			c.logger.Warn("write channel full during buffer flush", nil)
-			return NewError(ErrorConnection, "failed to flush buffer: write channel full")
+	for {
+		c.mu.Lock()
+		n := min(len(c.messageBuffer), c.queue.capacity())
+		batch := make([]outgoing, n)
+		copy(batch, c.messageBuffer[:n])
+		c.messageBuffer = c.messageBuffer[n:]
+		depth := len(c.messageBuffer)
+		c.mu.Unlock()
+		c.metrics.BufferDepth(depth)
+
+		if n == 0 {
+			return nil
+		}
+
+		for i, msg := range batch {
+			select {
+			case c.queue.lane(msg.in.Type) <- msg:
+				msg.delivery.setStatus(DeliveryPending)
+			case <-ctx.Done():
+				c.rebuffer(batch[i:])
+				return ctx.Err()
+			case <-writeDone:
+				c.rebuffer(batch[i:])
+				return NewError(ErrorDisconnected, "connection lost during buffer flush")
+			}
 		}
 	}
+}
 
-	return nil
+// rebuffer puts unsent messages back at the front of the buffer so they
+// survive until the next flush.
+func (c *Client) rebuffer(msgs []outgoing) {
+	c.mu.Lock()
+	c.messageBuffer = append(msgs, c.messageBuffer...)
+	depth := len(c.messageBuffer)
+	c.mu.Unlock()
+	c.metrics.BufferDepth(depth)
 }
 
-func (c *Client) readLoop(ctx context.Context) {
+// dialEndpoint connects to the best available endpoint and installs the
+// connection. Failures count against the endpoint's health.
+func (c *Client) dialEndpoint(ctx context.Context) (Endpoint, error) {
+	ep := c.endpoints.pick()
+	u, err := url.Parse(ep.URL)
+	if err != nil {
+		return ep, WrapError(ErrorInvalidConfig, "invalid WebSocket URL", err)
+	}
+
+	// Dial with handshake timeout
+	dialCtx := ctx
+	if c.config().HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, c.config().HandshakeTimeout)
+		defer cancel()
+	}
+
+	if err := c.breaker.Allow(); err != nil {
+		return ep, err
+	}
+
+	c.logger.Info("connecting", map[string]any{"url": u.String()})
+	start := time.Now()
+	fc, cd, err := c.dial(dialCtx, u.String())
+	if err != nil {
+		if ctx.Err() == nil {
+			c.breaker.Failure()
+		}
+		fields := map[string]any{"url": u.String(), "error": err.Error()}
+		if c.endpoints.failure(ep) {
+			fields["banned_for"] = c.config().EndpointBanDuration.String()
+		}
+		c.logger.Warn("dial failed", fields)
+		return ep, WrapError(ErrorConnection, "failed to dial server", err)
+	}
+	c.endpoints.success(ep, time.Since(start))
+	c.breaker.Success()
+
+	c.mu.Lock()
+	c.conn = internal.NewConn(fc, cd, c.config().ReadTimeout, c.config().WriteTimeout)
+	c.transportName = transport.ConnName(fc, c.config().Transport)
+	c.endpoint = ep
+	c.conn.SetRawHook(c.rawHook)
+	c.connectedAt = time.Now()
+	c.mu.Unlock()
+
+	// Keep REST paired with the active endpoint
+	if restBaseURL := c.config().endpointRESTURL(ep); restBaseURL != "" {
+		c.REST.SetBaseURL(restBaseURL)
+	}
+	return ep, nil
+}
+
+// dial connects through the configured transport and negotiates the frame
+// codec through the subprotocol. JSON is always offered as a fallback; servers
+// that select no subprotocol are assumed to speak JSON.
+func (c *Client) dial(ctx context.Context, url string) (transport.FrameConn, codec.Codec, error) {
+	cfg := c.config()
+	protocols := []string{cfg.Codec.Name()}
+	if cfg.Codec.Name() != codec.JSONName {
+		protocols = append(protocols, codec.JSONName)
+	}
+	fc, err := cfg.Transport.Dial(ctx, url, protocols)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch fc.Subprotocol() {
+	case cfg.Codec.Name():
+		return fc, cfg.Codec, nil
+	case "", codec.JSONName:
+		return fc, codec.JSON, nil
+	}
+	_ = fc.CloseNow()
+	return nil, nil, fmt.Errorf("server selected unsupported subprotocol %q", fc.Subprotocol())
+}
+
+func (c *Client) readLoop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	conn := c.currentConn()
 	for {
 		var out Outbound
-		if err := c.conn.Read(ctx, &out); err != nil {
-			// Check if this is user-initiated close (context cancelled)
+		err := conn.Read(ctx, &out)
+		if errors.Is(err, internal.ErrDecode) {
+			// The frame is malformed but the connection is still healthy
+			c.logger.Error("malformed frame", map[string]any{"error": err.Error()})
+			c.dispatcher.fireError(WrapError(ErrorSerialization, "failed to decode frame", err))
+			continue
+		}
+		if err != nil {
+			// Check if this is user-initiated close (context cancelled).
+			// A run replaced by UpdateConfig leaves the state to its successor.
 			if ctx.Err() != nil {
-				c.setState(StateDisconnected, nil)
+				if c.isCurrentRun(done) {
+					c.setState(StateDisconnected, nil)
+				}
 				return
 			}
 
-			// Check if this is a normal WebSocket close from user's Close() call
-			closeStatus := websocket.CloseStatus(err)
-			if closeStatus == websocket.StatusNormalClosure {
+			// Check if this is a normal close from user's Close() call
+			if errors.Is(err, transport.ErrClosed) {
 				c.setState(StateDisconnected, nil)
 				return
 			}
@@ -439,50 +784,124 @@ func (c *Client) readLoop(ctx context.Context) {
 			c.mu.Lock()
 			c.connected = false
 			c.mu.Unlock()
+			c.stopWriter()
+			c.resetResend(NewError(ErrorDisconnected, "connection lost before resend"))
 			c.setState(StateDisconnected, wireErr)
 
 			// Attempt reconnection if enabled
-			if !c.cfg.AutoReconnect {
+			if !c.config().AutoReconnect {
 				c.setState(StateError, wireErr)
 				return
 			}
+			if !c.reconnectLoop(ctx) {
+				return
+			}
+			conn = c.currentConn()
 
-			// Reconnection loop with exponential backoff
-			for {
-				if err := c.reconnect(ctx); err != nil {
-					if ctx.Err() != nil {
-						// Context cancelled, exit
-						return
-					}
-					// Reconnection failed, will retry with backoff
-					c.dispatcher.fireError(err)
-					continue
-				}
+			// Continue reading from new connection
+			c.logger.Info("read loop: reconnected successfully", nil)
+		} else {
+			label := frameLabel(out)
+			c.metrics.FrameReceived(label)
+			c.logger.Debug("frame received", map[string]any{"type": out.Type, "event": out.Event})
 
-				// Reconnection successful, restart write loop
-				go c.writeLoop(ctx)
+			out, err := c.interceptIncoming(ctx, out)
+			if errors.Is(err, ErrDropFrame) {
+				continue
+			}
+			if err != nil {
+				c.dispatcher.fireError(err)
+				continue
+			}
 
-				// Continue reading from new connection
-				c.logger.Warn("read loop: reconnected successfully", nil)
-				break
+			if !c.handleInflight(ctx, out) {
+				start := time.Now()
+				c.dispatcher.Dispatch(out)
+				c.metrics.DispatchLatency(label, time.Since(start))
 			}
-		} else {
-			c.dispatcher.Dispatch(out)
 		}
 	}
 }
 
-func (c *Client) writeLoop(ctx context.Context) {
+// reconnectLoop retries reconnect with backoff until it succeeds, the run
+// context is cancelled or the attempts are exhausted. On success reconnect
+// has already started the write and heartbeat loops for the new connection.
+func (c *Client) reconnectLoop(ctx context.Context) bool {
 	for {
-		select {
-		case in := <-c.writeCh:
-			if err := c.conn.Write(ctx, in); err != nil {
-				c.dispatcher.Dispatch(Outbound{Type: outboundError, Error: &Error{Code: "write_error", Msg: err.Error()}})
-				c.logger.Warn("write loop exit", map[string]interface{}{"error": err.Error()})
-				return
+		if err := c.reconnect(ctx); err != nil {
+			if ctx.Err() != nil {
+				// Context cancelled, exit
+				return false
+			}
+			if err == errReconnectExhausted {
+				// Give up; state is already StateError
+				c.dispatcher.fireError(err)
+				return false
+			}
+			// Reconnection failed, will retry with backoff. Breaker
+			// rejections are reported through OnBreakerStateChanged.
+			if !errors.Is(err, NewError(ErrorCircuitOpen, "")) {
+				c.dispatcher.fireError(err)
+			}
+			continue
+		}
+		return true
+	}
+}
+
+// writeLoop writes queued frames to conn until ctx is cancelled or a write fails.
+func (c *Client) writeLoop(ctx context.Context, conn *internal.Conn) {
+	var credits [laneCount]int
+	for {
+		out, ok := c.queue.next(ctx, &credits)
+		if !ok {
+			return
+		}
+		if ctx.Err() != nil {
+			// Cancelled while taking the frame: leave it to the next loop,
+			// unless Close has already failed everything queued
+			c.mu.Lock()
+			closed := c.closed
+			c.mu.Unlock()
+			if closed {
+				out.delivery.fail(NewError(ErrorDisconnected, "client closed"))
+			} else {
+				c.queue.carry(out)
+			}
+			return
+		}
+
+		in, err := c.interceptOutgoing(ctx, out.in)
+		if err != nil {
+			if errors.Is(err, ErrDropFrame) {
+				out.delivery.fail(NewError(ErrorRejected, "frame dropped by interceptor"))
+			} else {
+				out.delivery.fail(asWirechatError(err))
+				c.dispatcher.fireError(err)
 			}
-		case <-ctx.Done():
+			continue
+		}
+		out.in = in
+
+		// Track before writing so a fast echo cannot overtake the bookkeeping
+		c.resend.track(out)
+		if err := conn.Write(ctx, out.in); err != nil {
+			out.delivery.fail(WrapError(ErrorConnection, "failed to write message", err))
+			c.dispatcher.Dispatch(Outbound{Type: outboundError, Error: &Error{Code: "write_error", Msg: err.Error()}})
+			c.logger.Error("write loop exit", map[string]any{"type": out.in.Type, "error": err.Error()})
 			return
 		}
+		out.delivery.setStatus(DeliverySent)
+		c.metrics.FrameSent(out.in.Type)
+		c.logger.Debug("frame sent", map[string]any{"type": out.in.Type})
+	}
+}
+
+// rawHook forwards wire-level frames to the OnRawFrame callback.
+func (c *Client) rawHook(outgoing bool, data []byte) {
+	dir := DirectionIn
+	if outgoing {
+		dir = DirectionOut
 	}
+	c.dispatcher.fireRawFrame(dir, data)
 }
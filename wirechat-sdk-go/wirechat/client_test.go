@@ -1,9 +1,20 @@
 package wirechat
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
 )
 
 func TestDispatcherMessage(t *testing.T) {
@@ -35,6 +46,18 @@ func TestDispatcherError(t *testing.T) {
 	}
 }
 
+func TestDispatcherUnknownEvent(t *testing.T) {
+	var got Outbound
+	var d Dispatcher
+	d.SetOnUnknownEvent(func(out Outbound) { got = out })
+
+	raw := json.RawMessage(`{"target":"bob","action":"mute"}`)
+	d.Dispatch(Outbound{Type: outboundEvent, Event: "moderation", Data: raw})
+	if got.Event != "moderation" || string(got.Data) != string(raw) {
+		t.Fatalf("unexpected unknown event: %+v", got)
+	}
+}
+
 func TestClientSendNotConnected(t *testing.T) {
 	cfg := DefaultConfig()
 	c := NewClient(&cfg)
@@ -44,6 +67,278 @@ func TestClientSendNotConnected(t *testing.T) {
 	}
 }
 
+func TestReconnectMemoryTransport(t *testing.T) {
+	mem := transport.NewMemory()
+	cfg := DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.Transport = mem
+	cfg.AutoReconnect = true
+	cfg.ReconnectInterval = time.Millisecond
+	c := NewClient(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	accept := func() transport.FrameConn {
+		t.Helper()
+		conn, err := mem.Accept(ctx)
+		if err != nil {
+			t.Fatalf("accept: %v", err)
+		}
+		if _, data, err := conn.ReadFrame(ctx); err != nil || !bytes.Contains(data, []byte(`"hello"`)) {
+			t.Fatalf("expected hello, got %s (%v)", data, err)
+		}
+		return conn
+	}
+
+	var transportName string
+	c.OnStateChanged(func(ev StateEvent) {
+		if ev.NewState == StateConnected {
+			transportName = ev.Transport
+		}
+	})
+
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	first := accept()
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	if transportName != "memory" {
+		t.Fatalf("expected memory transport in state event, got %q", transportName)
+	}
+
+	if err := c.Join(ctx, "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if _, data, err := first.ReadFrame(ctx); err != nil || !bytes.Contains(data, []byte(`"join"`)) {
+		t.Fatalf("expected join, got %s (%v)", data, err)
+	}
+
+	// Dropping the connection triggers a reconnect that rejoins the room
+	_ = first.CloseNow()
+	second := accept()
+	if _, data, err := second.ReadFrame(ctx); err != nil || !bytes.Contains(data, []byte(`"general"`)) {
+		t.Fatalf("expected rejoin, got %s (%v)", data, err)
+	}
+}
+
+func TestOnRawFrame(t *testing.T) {
+	mem := transport.NewMemory()
+	cfg := DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.Transport = mem
+	c := NewClient(&cfg)
+
+	type frame struct {
+		dir  Direction
+		data string
+	}
+	frames := make(chan frame, 16)
+	c.OnRawFrame(func(dir Direction, data []byte) { frames <- frame{dir, string(data)} })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	server, err := mem.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if _, _, err := server.ReadFrame(ctx); err != nil {
+		t.Fatalf("read hello: %v", err)
+	}
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	raw, _ := json.Marshal(Outbound{Type: outboundEvent, Event: eventMessage, Data: json.RawMessage(`{"id":1,"room":"general","user":"bob","text":"hi"}`)})
+	if err := server.WriteFrame(ctx, transport.MessageText, raw); err != nil {
+		t.Fatalf("server write: %v", err)
+	}
+
+	// The hook sees the bytes on the wire in both directions
+	for _, want := range []frame{{DirectionOut, `"hello"`}, {DirectionIn, `"text":"hi"`}} {
+		select {
+		case got := <-frames:
+			if got.dir != want.dir || !strings.Contains(got.data, want.data) {
+				t.Fatalf("got %v frame %s, want %v frame with %s", got.dir, got.data, want.dir, want.data)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no %v frame with %s", want.dir, want.data)
+		}
+	}
+}
+
+func TestReconnectSingleWriter(t *testing.T) {
+	mem := transport.NewMemory()
+	cfg := DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.Transport = mem
+	cfg.AutoReconnect = true
+	cfg.ReconnectInterval = time.Millisecond
+	c := NewClient(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	accept := func() transport.FrameConn {
+		t.Helper()
+		conn, err := mem.Accept(ctx)
+		if err != nil {
+			t.Fatalf("accept: %v", err)
+		}
+		if _, _, err := conn.ReadFrame(ctx); err != nil {
+			t.Fatalf("read hello: %v", err)
+		}
+		return conn
+	}
+	writeDone := func() chan struct{} {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.writeDone
+	}
+
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	conn := accept()
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	// Each reconnect stops the write loop of the previous connection
+	for range 2 {
+		prev := writeDone()
+		_ = conn.CloseNow()
+		conn = accept()
+		select {
+		case <-prev:
+		case <-ctx.Done():
+			t.Fatal("write loop of the previous connection still running")
+		}
+	}
+	for c.State() != StateConnected {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := range 20 {
+		if err := c.Send(ctx, "general", fmt.Sprint(i)); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	for i := range 20 {
+		_, data, err := conn.ReadFrame(ctx)
+		if err != nil || !bytes.Contains(data, []byte(fmt.Sprintf(`"text":"%d"`, i))) {
+			t.Fatalf("frame %d out of order: %s (%v)", i, data, err)
+		}
+	}
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	hellos := make(chan HelloPayload, 1)
+	commands := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{codec.CBORName}})
+		if err != nil {
+			return
+		}
+		defer ws.CloseNow()
+
+		typ, data, err := ws.Read(r.Context())
+		if err != nil || typ != websocket.MessageBinary {
+			return
+		}
+		var in struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		var hello HelloPayload
+		if codec.CBOR.Unmarshal(data, &in) != nil || json.Unmarshal(in.Data, &hello) != nil {
+			return
+		}
+		hellos <- hello
+
+		ev, _ := json.Marshal(MessageEvent{Room: "general", User: "bob", Text: "hi"})
+		frame, _ := codec.CBOR.Marshal(Outbound{Type: outboundEvent, Event: eventMessage, Data: ev})
+		_ = ws.Write(r.Context(), websocket.MessageBinary, frame)
+		frame, _ = codec.CBOR.Marshal(map[string]any{"type": outboundEvent, "event": "poll", "data": map[string]any{"id": 7}})
+		_ = ws.Write(r.Context(), websocket.MessageBinary, frame)
+
+		// A raw JSON command arrives as a CBOR map
+		_, data, err = ws.Read(r.Context())
+		var cmd struct {
+			Data map[string]any `json:"data"`
+		}
+		if err == nil && codec.CBOR.Unmarshal(data, &cmd) == nil {
+			commands <- cmd.Data
+		}
+		_, _, _ = ws.Read(r.Context())
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.URL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	cfg.User = "alice"
+	cfg.Codec = codec.CBOR
+	c := NewClient(&cfg)
+
+	msgs := make(chan MessageEvent, 1)
+	c.OnMessage(func(ev MessageEvent) { msgs <- ev })
+	unknown := make(chan struct{ ID int }, 1)
+	c.OnUnknownEvent(func(out Outbound) {
+		var ev struct{ ID int }
+		if err := UnmarshalData(out.Data, &ev); err != nil {
+			t.Errorf("unknown event data %q: %v", out.Data, err)
+		}
+		unknown <- ev
+	})
+
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case hello := <-hellos:
+		if hello.User != "alice" {
+			t.Fatalf("unexpected hello: %+v", hello)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no hello received")
+	}
+	select {
+	case ev := <-msgs:
+		if ev.Room != "general" || ev.User != "bob" || ev.Text != "hi" {
+			t.Fatalf("unexpected message: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no message received")
+	}
+	select {
+	case ev := <-unknown:
+		if ev.ID != 7 {
+			t.Fatalf("unexpected unknown event: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no unknown event received")
+	}
+
+	if err := SendCommand(c, context.Background(), "custom", json.RawMessage(`{"a":1}`)); err != nil {
+		t.Fatalf("send command: %v", err)
+	}
+	select {
+	case data := <-commands:
+		if data["a"] != int64(1) {
+			t.Fatalf("unexpected command data: %#v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no command received")
+	}
+}
+
 // testCtx returns a cancellable context for unit tests.
 func testCtx() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
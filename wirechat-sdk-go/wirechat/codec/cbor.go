@@ -0,0 +1,944 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// CBOR simple values and additional information.
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	infoFloat16     = 25
+	infoFloat32     = 26
+	infoFloat64     = 27
+	infoIndefinite  = 31
+	breakByte       = 0xff
+)
+
+// maxDepth bounds nesting to protect against malicious input.
+const maxDepth = 64
+
+var (
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	jsonNumberType = reflect.TypeFor[json.Number]()
+	timeType       = reflect.TypeFor[time.Time]()
+)
+
+var errUnexpectedEnd = errors.New("cbor: unexpected end of data")
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return CBORName }
+func (cborCodec) Binary() bool { return true }
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	e := &encoder{}
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (cborCodec) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cbor: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	d := &decoder{data: data}
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("cbor: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return nil
+}
+
+// encoder writes CBOR using definite lengths.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, major<<5|25)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, major<<5|26)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, major<<5|27)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *encoder) null() {
+	e.buf = append(e.buf, majorSimple<<5|simpleNull)
+}
+
+func (e *encoder) encode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: value nested too deeply")
+	}
+	if !v.IsValid() {
+		e.null()
+		return nil
+	}
+
+	switch v.Type() {
+	case rawMessageType:
+		if v.Len() == 0 {
+			e.null()
+			return nil
+		}
+		x, err := decodeJSON(v.Bytes())
+		if err != nil {
+			return err
+		}
+		return e.encode(reflect.ValueOf(x), depth+1)
+	case jsonNumberType:
+		return e.encodeNumber(v.String())
+	case timeType:
+		t, ok := reflect.TypeAssert[time.Time](v)
+		if !ok {
+			return fmt.Errorf("cbor: cannot encode %s as time", v.Type())
+		}
+		s := t.Format(time.RFC3339Nano)
+		e.head(majorText, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		return e.encode(v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, majorSimple<<5|simpleTrue)
+		} else {
+			e.buf = append(e.buf, majorSimple<<5|simpleFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		if n >= 0 {
+			e.head(majorUint, uint64(n))
+		} else {
+			e.head(majorNegInt, uint64(-(n + 1)))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(majorUint, v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, majorSimple<<5|infoFloat32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, majorSimple<<5|infoFloat64)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		s := v.String()
+		e.head(majorText, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.head(majorBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		return e.encodeArray(v, depth)
+	case reflect.Array:
+		return e.encodeArray(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		return e.encodeMap(v, depth)
+	case reflect.Struct:
+		return e.encodeStruct(v, depth)
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// decodeJSON parses a JSON value for re-encoding, keeping numbers exact.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var x any
+	if err := dec.Decode(&x); err != nil {
+		return nil, fmt.Errorf("cbor: invalid json.RawMessage: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("cbor: invalid json.RawMessage: trailing data")
+	}
+	return x, nil
+}
+
+// encodeNumber writes a JSON number as an integer when it has no fraction
+// or exponent and fits, and as a float otherwise.
+func (e *encoder) encodeNumber(s string) error {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return e.encode(reflect.ValueOf(n), 0)
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		e.head(majorUint, n)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("cbor: invalid number %q", s)
+	}
+	return e.encode(reflect.ValueOf(f), 0)
+}
+
+func (e *encoder) encodeArray(v reflect.Value, depth int) error {
+	e.head(majorArray, uint64(v.Len()))
+	for i := range v.Len() {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value, depth int) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cbor: unsupported map key type %s", v.Type().Key())
+	}
+	// Sort keys so encoding is deterministic
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+
+	e.head(majorMap, uint64(len(keys)))
+	for _, k := range keys {
+		s := k.String()
+		e.head(majorText, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+		if err := e.encode(v.MapIndex(k), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeStruct(v reflect.Value, depth int) error {
+	fields := cachedFields(v.Type())
+
+	n := 0
+	for _, f := range fields {
+		if fv, ok := fieldByIndex(v, f.index); ok && !(f.omitEmpty && isEmpty(fv)) {
+			n++
+		}
+	}
+
+	e.head(majorMap, uint64(n))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmpty(fv)) {
+			continue
+		}
+		e.head(majorText, uint64(len(f.name)))
+		e.buf = append(e.buf, f.name...)
+		if err := e.encode(fv, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decoder reads CBOR, accepting both definite and indefinite lengths.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// head reads an initial byte and its argument.
+func (d *decoder) head() (major, info byte, arg uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, errUnexpectedEnd
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		if d.pos+1 > len(d.data) {
+			return 0, 0, 0, errUnexpectedEnd
+		}
+		arg = uint64(d.data[d.pos])
+		d.pos++
+	case info == 25:
+		if d.pos+2 > len(d.data) {
+			return 0, 0, 0, errUnexpectedEnd
+		}
+		arg = uint64(binary.BigEndian.Uint16(d.data[d.pos:]))
+		d.pos += 2
+	case info == 26:
+		if d.pos+4 > len(d.data) {
+			return 0, 0, 0, errUnexpectedEnd
+		}
+		arg = uint64(binary.BigEndian.Uint32(d.data[d.pos:]))
+		d.pos += 4
+	case info == 27:
+		if d.pos+8 > len(d.data) {
+			return 0, 0, 0, errUnexpectedEnd
+		}
+		arg = binary.BigEndian.Uint64(d.data[d.pos:])
+		d.pos += 8
+	case info == infoIndefinite:
+		if major == majorUint || major == majorNegInt || major == majorTag {
+			return 0, 0, 0, fmt.Errorf("cbor: indefinite length not allowed for major type %d", major)
+		}
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: reserved additional information %d", info)
+	}
+	return major, info, arg, nil
+}
+
+// atBreak consumes a break byte if present.
+func (d *decoder) atBreak() (bool, error) {
+	if d.pos >= len(d.data) {
+		return false, errUnexpectedEnd
+	}
+	if d.data[d.pos] == breakByte {
+		d.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (d *decoder) skipItem(depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: data nested too deeply")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case majorUint, majorNegInt, majorSimple:
+		return nil
+	case majorBytes, majorText:
+		if info == infoIndefinite {
+			for {
+				if brk, err := d.atBreak(); err != nil || brk {
+					return err
+				}
+				if err := d.skipItem(depth + 1); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := d.take(arg)
+		return err
+	case majorArray, majorMap:
+		per := uint64(1)
+		if major == majorMap {
+			per = 2
+		}
+		if info == infoIndefinite {
+			for {
+				if brk, err := d.atBreak(); err != nil || brk {
+					return err
+				}
+				for range per {
+					if err := d.skipItem(depth + 1); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if arg > uint64(len(d.data)) {
+			return errUnexpectedEnd
+		}
+		for range arg * per {
+			if err := d.skipItem(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case majorTag:
+		return d.skipItem(depth + 1)
+	}
+	return nil
+}
+
+func (d *decoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errUnexpectedEnd
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// readString reads a byte or text string, joining indefinite-length chunks.
+func (d *decoder) readString(major, info byte, arg uint64) ([]byte, error) {
+	if info != infoIndefinite {
+		return d.take(arg)
+	}
+	var out []byte
+	for {
+		brk, err := d.atBreak()
+		if err != nil {
+			return nil, err
+		}
+		if brk {
+			return out, nil
+		}
+		m, i, a, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || i == infoIndefinite {
+			return nil, errors.New("cbor: invalid indefinite-length string chunk")
+		}
+		chunk, err := d.take(a)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+func (d *decoder) decode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: data nested too deeply")
+	}
+
+	if v.Type() == rawMessageType {
+		x, err := d.decodeAny(depth)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(x)
+		if err != nil {
+			return fmt.Errorf("cbor: convert to json.RawMessage: %w", err)
+		}
+		v.SetBytes(data)
+		return nil
+	}
+
+	// Null and undefined reset the target to its zero value
+	if d.pos < len(d.data) {
+		if b := d.data[d.pos]; b == majorSimple<<5|simpleNull || b == majorSimple<<5|simpleUndefined {
+			d.pos++
+			v.SetZero()
+			return nil
+		}
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem(), depth+1)
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		x, err := d.decodeAny(depth)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case majorTag:
+		// Tags carry no meaning for SDK types; decode the tagged item
+		return d.decode(v, depth+1)
+	case majorUint, majorNegInt:
+		return setInt(v, major, arg)
+	case majorSimple:
+		return d.setSimple(v, info, arg)
+	case majorBytes, majorText:
+		b, err := d.readString(major, info, arg)
+		if err != nil {
+			return err
+		}
+		return setString(v, major, b)
+	case majorArray:
+		return d.decodeArray(v, info, arg, depth)
+	case majorMap:
+		return d.decodeMap(v, info, arg, depth)
+	}
+	return fmt.Errorf("cbor: unexpected major type %d", major)
+}
+
+func setInt(v reflect.Value, major byte, arg uint64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if arg > math.MaxInt64 {
+			return fmt.Errorf("cbor: integer overflows %s", v.Type())
+		}
+		n := int64(arg)
+		if major == majorNegInt {
+			n = -1 - n
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("cbor: integer overflows %s", v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if major == majorNegInt || v.OverflowUint(arg) {
+			return fmt.Errorf("cbor: integer overflows %s", v.Type())
+		}
+		v.SetUint(arg)
+	case reflect.Float32, reflect.Float64:
+		f := float64(arg)
+		if major == majorNegInt {
+			f = -1 - f
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("cbor: cannot decode integer into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) setSimple(v reflect.Value, info byte, arg uint64) error {
+	switch info {
+	case simpleFalse, simpleTrue:
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("cbor: cannot decode bool into %s", v.Type())
+		}
+		v.SetBool(info == simpleTrue)
+		return nil
+	case infoFloat16, infoFloat32, infoFloat64:
+		f := decodeFloat(info, arg)
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(f)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if f != math.Trunc(f) || v.OverflowInt(int64(f)) {
+				return fmt.Errorf("cbor: cannot decode %v into %s", f, v.Type())
+			}
+			v.SetInt(int64(f))
+			return nil
+		}
+		return fmt.Errorf("cbor: cannot decode float into %s", v.Type())
+	}
+	return fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+func decodeFloat(info byte, arg uint64) float64 {
+	switch info {
+	case infoFloat16:
+		return float16(uint16(arg))
+	case infoFloat32:
+		return float64(math.Float32frombits(uint32(arg)))
+	default:
+		return math.Float64frombits(arg)
+	}
+}
+
+// float16 converts an IEEE 754 half-precision value.
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+func setString(v reflect.Value, major byte, b []byte) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(b))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(slices.Clone(b))
+	case v.Type() == timeType && major == majorText:
+		t, err := time.Parse(time.RFC3339Nano, string(b))
+		if err != nil {
+			return fmt.Errorf("cbor: %w", err)
+		}
+		v.Set(reflect.ValueOf(t))
+	default:
+		return fmt.Errorf("cbor: cannot decode string into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) decodeArray(v reflect.Value, info byte, arg uint64, depth int) error {
+	indefinite := info == infoIndefinite
+	if !indefinite && arg > uint64(len(d.data)-d.pos) {
+		return errUnexpectedEnd
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		n := 0
+		if !indefinite {
+			n = int(arg)
+		}
+		s := reflect.MakeSlice(v.Type(), 0, n)
+		for i := 0; indefinite || i < n; i++ {
+			if indefinite {
+				if brk, err := d.atBreak(); err != nil {
+					return err
+				} else if brk {
+					break
+				}
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem, depth+1); err != nil {
+				return err
+			}
+			s = reflect.Append(s, elem)
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		for i := 0; indefinite || i < int(arg); i++ {
+			if indefinite {
+				if brk, err := d.atBreak(); err != nil {
+					return err
+				} else if brk {
+					break
+				}
+			}
+			if i < v.Len() {
+				if err := d.decode(v.Index(i), depth+1); err != nil {
+					return err
+				}
+			} else if err := d.skipItem(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cbor: cannot decode array into %s", v.Type())
+}
+
+func (d *decoder) decodeMap(v reflect.Value, info byte, arg uint64, depth int) error {
+	indefinite := info == infoIndefinite
+	if !indefinite && arg > uint64(len(d.data)-d.pos) {
+		return errUnexpectedEnd
+	}
+
+	var fields []field
+	switch v.Kind() {
+	case reflect.Struct:
+		fields = cachedFields(v.Type())
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cbor: unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	default:
+		return fmt.Errorf("cbor: cannot decode map into %s", v.Type())
+	}
+
+	for i := 0; indefinite || i < int(arg); i++ {
+		if indefinite {
+			if brk, err := d.atBreak(); err != nil {
+				return err
+			} else if brk {
+				break
+			}
+		}
+
+		var key string
+		if err := d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+			return fmt.Errorf("cbor: map key: %w", err)
+		}
+
+		if v.Kind() == reflect.Map {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem, depth+1); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			continue
+		}
+
+		f, ok := lookupField(fields, key)
+		if !ok {
+			if err := d.skipItem(depth + 1); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decode(allocFieldByIndex(v, f.index), depth+1); err != nil {
+			return fmt.Errorf("cbor: field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeAny decodes an item into generic Go values. Like encoding/json, maps
+// become map[string]any and arrays []any, but integers stay exact: they
+// decode to int64, or uint64 above math.MaxInt64, instead of float64.
+func (d *decoder) decodeAny(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("cbor: data nested too deeply")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if arg <= math.MaxInt64 {
+			return int64(arg), nil
+		}
+		return arg, nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return -1 - float64(arg), nil
+		}
+		return -1 - int64(arg), nil
+	case majorBytes:
+		b, err := d.readString(major, info, arg)
+		return slices.Clone(b), err
+	case majorText:
+		b, err := d.readString(major, info, arg)
+		return string(b), err
+	case majorTag:
+		return d.decodeAny(depth + 1)
+	case majorSimple:
+		switch info {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull, simpleUndefined:
+			return nil, nil
+		case infoFloat16, infoFloat32, infoFloat64:
+			return decodeFloat(info, arg), nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	case majorArray:
+		var out []any
+		for i := 0; info == infoIndefinite || i < int(arg); i++ {
+			if info == infoIndefinite {
+				if brk, err := d.atBreak(); err != nil {
+					return nil, err
+				} else if brk {
+					break
+				}
+			}
+			x, err := d.decodeAny(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, x)
+		}
+		if out == nil {
+			out = []any{}
+		}
+		return out, nil
+	case majorMap:
+		out := make(map[string]any)
+		for i := 0; info == infoIndefinite || i < int(arg); i++ {
+			if info == infoIndefinite {
+				if brk, err := d.atBreak(); err != nil {
+					return nil, err
+				} else if brk {
+					break
+				}
+			}
+			k, err := d.decodeAny(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			x, err := d.decodeAny(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = x
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cbor: unexpected major type %d", major)
+}
+
+// field describes an encodable struct field, following encoding/json tag rules.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		if fields, ok := f.([]field); ok {
+			return fields
+		}
+	}
+	fields := typeFields(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func typeFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(slices.Clone(index), i)
+
+		// Flatten untagged embedded structs like encoding/json
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, typeFields(ft, idx)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     idx,
+			omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+		})
+	}
+	return fields
+}
+
+func lookupField(fields []field, key string) (field, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// fieldByIndex returns a nested field, reporting false if it sits behind a nil pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// allocFieldByIndex returns a nested field, allocating embedded pointers as needed.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	ID int64 `json:"id"`
+}
+
+type sample struct {
+	inner
+	Name    string            `json:"name"`
+	Score   float64           `json:"score"`
+	Neg     int               `json:"neg"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta"`
+	At      time.Time         `json:"at"`
+	Skip    string            `json:"-"`
+	Missing *string           `json:"missing"`
+	Raw     json.RawMessage   `json:"raw"`
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	raw := json.RawMessage(`{"id":9007199254740993,"score":1.5,"tags":["a",null],"x":true}`)
+	in := sample{
+		inner: inner{ID: 42},
+		Name:  "alice",
+		Score: 1.5,
+		Neg:   -300,
+		Tags:  []string{"a", "b"},
+		Meta:  map[string]string{"k": "v"},
+		At:    time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+		Skip:  "ignored",
+		Raw:   raw,
+	}
+	data, err := CBOR.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var out sample
+	if err := CBOR.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	in.Skip = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+
+	// json.RawMessage is JSON on both sides and travels as a CBOR map
+	var generic map[string]any
+	if err := CBOR.Unmarshal(data, &generic); err != nil {
+		t.Fatalf("unmarshal generic: %v", err)
+	}
+	if m, ok := generic["raw"].(map[string]any); !ok || m["id"] != int64(9007199254740993) || m["x"] != true {
+		t.Fatalf("raw JSON not encoded as CBOR: %#v", generic["raw"])
+	}
+
+	if _, err := CBOR.Marshal(sample{Raw: json.RawMessage(`{"x":`)}); err == nil {
+		t.Fatalf("expected error for invalid raw JSON")
+	}
+}
+
+func TestCBORKnownEncoding(t *testing.T) {
+	// {"a": 1, "b": [2, 3]} from RFC 8949 Appendix A
+	want := []byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x62, 0x82, 0x02, 0x03}
+	got, err := CBOR.Marshal(map[string]any{"b": []int{2, 3}, "a": 1})
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("got %x, %v; want %x", got, err, want)
+	}
+
+	// Indefinite-length map and array: {_ "a": 1, "b": [_ 2, 3]}
+	var v struct {
+		A int   `json:"a"`
+		B []int `json:"b"`
+	}
+	if err := CBOR.Unmarshal([]byte{0xbf, 0x61, 0x61, 0x01, 0x61, 0x62, 0x9f, 0x02, 0x03, 0xff, 0xff}, &v); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if v.A != 1 || !reflect.DeepEqual(v.B, []int{2, 3}) {
+		t.Fatalf("unexpected value: %+v", v)
+	}
+
+	if err := CBOR.Unmarshal([]byte{0x82, 0x01}, &v.B); err == nil {
+		t.Fatalf("expected error for truncated input")
+	}
+}
@@ -0,0 +1,58 @@
+// Package codec defines wire encodings for WireChat frames.
+//
+// A Codec is selected in wirechat.Config and negotiated with the server through
+// the WebSocket subprotocol. JSON is the default; CBOR is a compact binary
+// alternative implemented in pure Go.
+//
+// json.RawMessage always holds JSON, whatever the codec: CBOR converts an
+// item decoded into a json.RawMessage to JSON, and encodes a json.RawMessage
+// by converting its JSON. This keeps Outbound.Data and raw command payloads
+// codec-neutral. Decoding into interface values yields map[string]any and
+// []any like encoding/json, except that CBOR integers decode to int64 (uint64
+// above math.MaxInt64) rather than float64.
+package codec
+
+import "encoding/json"
+
+// Codec encodes and decodes frames.
+type Codec interface {
+	// Name is the WebSocket subprotocol announced for this codec.
+	Name() string
+	// Binary reports whether frames are sent as binary WebSocket messages.
+	Binary() bool
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v any) error
+}
+
+// Subprotocol names announced during the WebSocket handshake.
+const (
+	JSONName = "wirechat.json"
+	CBORName = "wirechat.cbor"
+)
+
+// JSON is the default text codec.
+var JSON Codec = jsonCodec{}
+
+// CBOR is the binary codec (RFC 8949).
+var CBOR Codec = cborCodec{}
+
+// ByName returns the codec for a subprotocol name.
+func ByName(name string) (Codec, bool) {
+	switch name {
+	case JSONName:
+		return JSON, true
+	case CBORName:
+		return CBOR, true
+	default:
+		return nil, false
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return JSONName }
+func (jsonCodec) Binary() bool                       { return false }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
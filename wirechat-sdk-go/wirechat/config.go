@@ -1,6 +1,16 @@
 package wirechat
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
 
 // Config controls how the SDK connects.
 type Config struct {
@@ -13,9 +23,24 @@ type Config struct {
 	ReadTimeout      time.Duration // 0 = no timeout, positive = custom timeout
 	WriteTimeout     time.Duration // 0 = no timeout, positive = custom timeout
 
+	// Heartbeat configuration
+	HeartbeatInterval  time.Duration // Client ping interval (0 = disabled, default: 0)
+	HeartbeatTimeout   time.Duration // Pong wait per ping (default: 5s, 0 = HeartbeatInterval)
+	HeartbeatMaxMisses int           // Consecutive missed pings before reconnect (default: 3)
+
 	// REST API configuration
 	RESTBaseURL string // REST API base URL (e.g., "http://localhost:8080/api")
 
+	// Failover configuration
+	Endpoints           []Endpoint    // Servers to fail over between; when empty, URL and RESTBaseURL form the only endpoint. Endpoints without a RESTBaseURL use Config.RESTBaseURL
+	EndpointMaxFailures int           // Consecutive failures before an endpoint is banned (default: 3, 0 = never)
+	EndpointBanDuration time.Duration // How long a failing endpoint is skipped (default: 1m)
+
+	// Circuit breaker configuration (shared by WebSocket dials and REST calls)
+	BreakerThreshold   int           // Consecutive failures that open the breaker (0 = disabled, default: 0)
+	BreakerCooldown    time.Duration // Open period before a half-open probe (default: 30s)
+	BreakerMaxCooldown time.Duration // Cap for the cool-down, which doubles after each failed probe (default: 5m)
+
 	// Auto-reconnect configuration
 	AutoReconnect     bool          // Enable automatic reconnection on disconnect
 	ReconnectInterval time.Duration // Initial reconnect delay (default: 1s)
@@ -25,26 +50,285 @@ type Config struct {
 	// Message buffering configuration
 	BufferMessages bool // Enable buffering of outgoing messages during disconnect
 	MaxBufferSize  int  // Maximum number of messages to buffer (default: 100)
+
+	// Write queue configuration
+	WriteQueueSize   int            // Capacity of each write lane (default: 16)
+	WriteQueuePolicy OverflowPolicy // Behavior when the write queue is full (default: OverflowBlock)
+	WriteFairness    Fairness       // Lane scheduling (default: FairnessStrict)
+	LaneWeights      map[Lane]int   // Frames per round for each lane with FairnessWeighted
+
+	// Wire encoding. The default transport is plain WebSocket: falling back to
+	// long-polling when the upgrade is blocked is opt-in, since it silently
+	// changes latency and server requirements. Set Transport to
+	// transport.Fallback{transport.WebSocket{}, transport.LongPoll{}}, or
+	// transport = "auto" in a config file, to enable it.
+	Transport transport.Transport // Connection transport (default: WebSocket)
+	Codec     codec.Codec         // Frame codec, negotiated via subprotocol (default: codec.JSON)
+
+	// Observability
+	Metrics Metrics      // Metrics sink (default: no-op)
+	Tracer  trace.Tracer // Tracer for WS and REST operations (default: no-op)
+
+	// Room state
+	TrackRoster  bool          // Track members of joined rooms (see Client.Members)
+	MessageStore MessageStore  // Cache fed from message and history events and REST pages (default: none)
+	RoomCacheTTL time.Duration // How long ListRooms results resolve room names and IDs (default: 5m, 0 = always refresh)
+
+	// Rate limit resend configuration
+	ResendRateLimited bool          // Resend messages rejected with rate_limited
+	MaxResendAttempts int           // Maximum resend attempts per message (default: 3)
+	ResendInterval    time.Duration // Initial resend delay (default: 1s)
+	MaxResendDelay    time.Duration // Maximum resend delay (default: 10s)
 }
 
 // DefaultConfig returns sensible defaults.
 // Protocol is set to 1 (current protocol version).
 // ReadTimeout is set to 0 (infinite) because chat messages arrive sporadically.
 // The server handles connection keepalive via ping/pong frames.
+// HeartbeatInterval is 0 (disabled) - set it to detect half-open connections from the client side.
 // HandshakeTimeout and WriteTimeout are set to reasonable values to detect network issues during active operations.
 // AutoReconnect is disabled by default - clients must opt-in.
 // BufferMessages is disabled by default - clients must opt-in.
+// ResendRateLimited is disabled by default - clients must opt-in.
+// The circuit breaker is disabled by default - set BreakerThreshold to enable it.
 func DefaultConfig() Config {
 	return Config{
-		Protocol:          1,
-		HandshakeTimeout:  10 * time.Second,
-		ReadTimeout:       0, // 0 = infinite, wait for server ping/pong
-		WriteTimeout:      10 * time.Second,
-		AutoReconnect:     false, // Disabled by default
-		ReconnectInterval: 1 * time.Second,
-		MaxReconnectDelay: 30 * time.Second,
-		MaxReconnectTries: 0,     // 0 = infinite retries
-		BufferMessages:    false, // Disabled by default
-		MaxBufferSize:     100,
+		Protocol:            1,
+		HandshakeTimeout:    10 * time.Second,
+		ReadTimeout:         0, // 0 = infinite, wait for server ping/pong
+		WriteTimeout:        10 * time.Second,
+		HeartbeatInterval:   0, // Disabled by default
+		HeartbeatTimeout:    5 * time.Second,
+		HeartbeatMaxMisses:  3,
+		EndpointMaxFailures: 3,
+		EndpointBanDuration: 1 * time.Minute,
+		BreakerThreshold:    0, // Disabled by default
+		BreakerCooldown:     30 * time.Second,
+		BreakerMaxCooldown:  5 * time.Minute,
+		AutoReconnect:       false, // Disabled by default
+		ReconnectInterval:   1 * time.Second,
+		MaxReconnectDelay:   30 * time.Second,
+		MaxReconnectTries:   0,     // 0 = infinite retries
+		BufferMessages:      false, // Disabled by default
+		MaxBufferSize:       100,
+		RoomCacheTTL:        5 * time.Minute,
+		WriteQueueSize:      defaultWriteQueueSize,
+		WriteQueuePolicy:    OverflowBlock,
+		WriteFairness:       FairnessStrict,
+		LaneWeights:         defaultLaneWeights(),
+		Transport:           defaultTransport(),
+		Codec:               codec.JSON,
+		ResendRateLimited:   false, // Disabled by default
+		MaxResendAttempts:   3,
+		ResendInterval:      1 * time.Second,
+		MaxResendDelay:      10 * time.Second,
+	}
+}
+
+// defaultTransport is plain WebSocket. Long-polling is opt-in, see autoTransport.
+func defaultTransport() transport.Transport {
+	return transport.WebSocket{}
+}
+
+// autoTransport tries WebSocket first and downgrades to long-polling when
+// the upgrade fails, e.g. behind proxies that strip it.
+func autoTransport() transport.Transport {
+	return transport.Fallback{transport.WebSocket{}, transport.LongPoll{}}
+}
+
+// Validate checks the configuration and returns every problem at once as a
+// joined error of ErrorInvalidConfig values. Connect calls it before dialing.
+func (cfg *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, NewError(ErrorInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+
+	// Endpoints
+	if len(cfg.Endpoints) == 0 {
+		if cfg.URL == "" {
+			add("URL: empty")
+		} else {
+			errs = append(errs, cfg.checkURL("URL", cfg.URL)...)
+			errs = append(errs, checkSchemePair("RESTBaseURL", cfg.URL, cfg.RESTBaseURL)...)
+		}
+	}
+	errs = append(errs, checkRESTURL("RESTBaseURL", cfg.RESTBaseURL)...)
+	withREST := slices.ContainsFunc(cfg.Endpoints, func(ep Endpoint) bool { return cfg.endpointRESTURL(ep) != "" })
+	for i, ep := range cfg.Endpoints {
+		if ep.URL == "" {
+			add("Endpoints[%d].URL: empty", i)
+		} else {
+			errs = append(errs, cfg.checkURL(fmt.Sprintf("Endpoints[%d].URL", i), ep.URL)...)
+			errs = append(errs, checkSchemePair(fmt.Sprintf("Endpoints[%d].RESTBaseURL", i), ep.URL, cfg.endpointRESTURL(ep))...)
+		}
+		errs = append(errs, checkRESTURL(fmt.Sprintf("Endpoints[%d].RESTBaseURL", i), ep.RESTBaseURL)...)
+		if withREST && cfg.endpointRESTURL(ep) == "" {
+			// REST would keep talking to the previous endpoint after a failover
+			add("Endpoints[%d].RESTBaseURL: empty while other endpoints have one; set it or RESTBaseURL", i)
+		}
+	}
+
+	// Durations
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"HandshakeTimeout", cfg.HandshakeTimeout},
+		{"ReadTimeout", cfg.ReadTimeout},
+		{"WriteTimeout", cfg.WriteTimeout},
+		{"HeartbeatInterval", cfg.HeartbeatInterval},
+		{"HeartbeatTimeout", cfg.HeartbeatTimeout},
+		{"EndpointBanDuration", cfg.EndpointBanDuration},
+		{"BreakerCooldown", cfg.BreakerCooldown},
+		{"BreakerMaxCooldown", cfg.BreakerMaxCooldown},
+		{"ReconnectInterval", cfg.ReconnectInterval},
+		{"MaxReconnectDelay", cfg.MaxReconnectDelay},
+		{"RoomCacheTTL", cfg.RoomCacheTTL},
+		{"ResendInterval", cfg.ResendInterval},
+		{"MaxResendDelay", cfg.MaxResendDelay},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			add("%s: negative duration %s", d.name, d.d)
+		}
+	}
+
+	// Counts
+	counts := []struct {
+		name string
+		n    int
+	}{
+		{"Protocol", cfg.Protocol},
+		{"HeartbeatMaxMisses", cfg.HeartbeatMaxMisses},
+		{"EndpointMaxFailures", cfg.EndpointMaxFailures},
+		{"BreakerThreshold", cfg.BreakerThreshold},
+		{"MaxReconnectTries", cfg.MaxReconnectTries},
+		{"MaxBufferSize", cfg.MaxBufferSize},
+		{"WriteQueueSize", cfg.WriteQueueSize},
+		{"MaxResendAttempts", cfg.MaxResendAttempts},
+	}
+	for _, c := range counts {
+		if c.n < 0 {
+			add("%s: negative value %d", c.name, c.n)
+		}
+	}
+	for lane := range laneCount {
+		if w := cfg.LaneWeights[lane]; w < 0 {
+			add("LaneWeights[%s]: negative weight %d", lane, w)
+		}
+	}
+
+	// Enums
+	if cfg.WriteQueuePolicy < OverflowBlock || cfg.WriteQueuePolicy > OverflowError {
+		add("WriteQueuePolicy: unknown policy %d", cfg.WriteQueuePolicy)
+	}
+	if cfg.WriteFairness != FairnessStrict && cfg.WriteFairness != FairnessWeighted {
+		add("WriteFairness: unknown fairness %d", cfg.WriteFairness)
+	}
+
+	// Mutually dependent fields
+	if cfg.HeartbeatInterval > 0 && cfg.HeartbeatMaxMisses == 0 {
+		add("HeartbeatMaxMisses: must be positive when HeartbeatInterval is set")
+	}
+	if cfg.BufferMessages && cfg.MaxBufferSize == 0 {
+		add("MaxBufferSize: must be positive when BufferMessages is enabled")
+	}
+	if cfg.AutoReconnect && cfg.ReconnectInterval == 0 {
+		add("ReconnectInterval: must be positive when AutoReconnect is enabled")
+	}
+	if cfg.AutoReconnect && cfg.MaxReconnectDelay == 0 {
+		add("MaxReconnectDelay: must be positive when AutoReconnect is enabled")
+	}
+	if cfg.MaxReconnectDelay > 0 && cfg.MaxReconnectDelay < cfg.ReconnectInterval {
+		add("MaxReconnectDelay: %s is less than ReconnectInterval %s", cfg.MaxReconnectDelay, cfg.ReconnectInterval)
+	}
+	if cfg.ResendRateLimited && cfg.ResendInterval == 0 {
+		add("ResendInterval: must be positive when ResendRateLimited is enabled")
+	}
+	if cfg.MaxResendDelay > 0 && cfg.MaxResendDelay < cfg.ResendInterval {
+		add("MaxResendDelay: %s is less than ResendInterval %s", cfg.MaxResendDelay, cfg.ResendInterval)
+	}
+	if cfg.BreakerThreshold > 0 && cfg.BreakerMaxCooldown > 0 && cfg.BreakerMaxCooldown < cfg.BreakerCooldown {
+		add("BreakerMaxCooldown: %s is less than BreakerCooldown %s", cfg.BreakerMaxCooldown, cfg.BreakerCooldown)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkURL validates a server URL. WebSocket-based transports require ws or
+// wss; other transports define their own schemes.
+func (cfg *Config) checkURL(field, raw string) []error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return []error{WrapError(ErrorInvalidConfig, field+": invalid URL", err)}
+	}
+	if usesWebSocket(cfg.Transport) && u.Scheme != "ws" && u.Scheme != "wss" {
+		return []error{NewError(ErrorInvalidConfig, fmt.Sprintf("%s: scheme %q must be ws or wss", field, u.Scheme))}
+	}
+	if u.Host == "" && (u.Scheme == "ws" || u.Scheme == "wss") {
+		return []error{NewError(ErrorInvalidConfig, field+": missing host")}
+	}
+	return nil
+}
+
+// checkRESTURL validates an optional REST base URL.
+func checkRESTURL(field, raw string) []error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return []error{WrapError(ErrorInvalidConfig, field+": invalid URL", err)}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return []error{NewError(ErrorInvalidConfig, fmt.Sprintf("%s: scheme %q must be http or https", field, u.Scheme))}
+	}
+	if u.Host == "" {
+		return []error{NewError(ErrorInvalidConfig, field+": missing host")}
+	}
+	return nil
+}
+
+// tlsSchemes maps the URL schemes paired between WebSocket and REST to whether they use TLS.
+var tlsSchemes = map[string]bool{"ws": false, "http": false, "wss": true, "https": true}
+
+// checkSchemePair validates that the WebSocket and REST URLs of one endpoint
+// agree on TLS: wss pairs with https and ws with http. Unparsable URLs and
+// other schemes are reported by checkURL and checkRESTURL.
+func checkSchemePair(field, wsURL, restURL string) []error {
+	if restURL == "" {
+		return nil
+	}
+	ws, err := url.Parse(wsURL)
+	if err != nil {
+		return nil
+	}
+	r, err := url.Parse(restURL)
+	if err != nil {
+		return nil
+	}
+	wsTLS, ok := tlsSchemes[ws.Scheme]
+	if !ok {
+		return nil
+	}
+	if restTLS, ok := tlsSchemes[r.Scheme]; ok && restTLS != wsTLS {
+		return []error{NewError(ErrorInvalidConfig, fmt.Sprintf("%s: scheme %q does not match %q; pair wss with https and ws with http", field, r.Scheme, ws.Scheme))}
+	}
+	return nil
+}
+
+// usesWebSocket reports whether t dials WebSocket URLs (nil means the default transport).
+func usesWebSocket(t transport.Transport) bool {
+	switch t := t.(type) {
+	case nil, transport.WebSocket, *transport.WebSocket:
+		return true
+	case transport.Fallback:
+		for _, f := range t {
+			if usesWebSocket(f) {
+				return true
+			}
+		}
 	}
+	return false
 }
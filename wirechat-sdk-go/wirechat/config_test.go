@@ -0,0 +1,68 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.URL = "ws://localhost:8080/ws"
+	cfg.RESTBaseURL = "http://localhost:8080/api"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config should be valid: %v", err)
+	}
+
+	cfg.URL = "http://localhost:8080/ws"
+	cfg.RESTBaseURL = "ws://localhost:8080/api"
+	cfg.WriteTimeout = -time.Second
+	cfg.BufferMessages = true
+	cfg.MaxBufferSize = 0
+	cfg.ReconnectInterval = 10 * time.Second
+	cfg.MaxReconnectDelay = time.Second
+
+	err := cfg.Validate()
+	if !errors.Is(err, NewError(ErrorInvalidConfig, "")) {
+		t.Fatalf("expected invalid_config, got %v", err)
+	}
+	for _, field := range []string{"URL", "RESTBaseURL", "WriteTimeout", "MaxBufferSize", "MaxReconnectDelay"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("missing %s problem in %v", field, err)
+		}
+	}
+
+	// WebSocket and REST schemes must agree on TLS for every endpoint
+	cfg = DefaultConfig()
+	cfg.URL = "wss://example.com/ws"
+	cfg.RESTBaseURL = "http://example.com/api"
+	cfg.AutoReconnect = true
+	cfg.MaxReconnectDelay = 0
+	err = cfg.Validate()
+	for _, field := range []string{"RESTBaseURL", "MaxReconnectDelay"} {
+		if err == nil || !strings.Contains(err.Error(), field+":") {
+			t.Errorf("missing %s problem in %v", field, err)
+		}
+	}
+	cfg.URL = ""
+	cfg.MaxReconnectDelay = time.Minute
+	cfg.Endpoints = []Endpoint{
+		{URL: "wss://a.example.com/ws", RESTBaseURL: "https://a.example.com/api"},
+		{URL: "ws://b.example.com/ws"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected paired schemes to be valid, got %v", err)
+	}
+	cfg.Endpoints[1].URL = "wss://b.example.com/ws"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "Endpoints[1].RESTBaseURL:") {
+		t.Fatalf("expected scheme mismatch for the RESTBaseURL fallback, got %v", err)
+	}
+
+	// Connect reports the problems before dialing
+	c := NewClient(&cfg)
+	if err := c.Connect(context.Background()); !errors.Is(err, NewError(ErrorInvalidConfig, "")) {
+		t.Fatalf("expected Connect to validate, got %v", err)
+	}
+}
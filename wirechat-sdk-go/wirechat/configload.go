@@ -0,0 +1,418 @@
+package wirechat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+// tokenFileKey names the setting that reads Token from a file, e.g. a mounted Kubernetes secret.
+const tokenFileKey = "token_file"
+
+// ConfigFromEnv layers environment variables over DefaultConfig. Every Config
+// field maps to PREFIX_FIELD_NAME in upper snake case, e.g. with prefix
+// "WIRECHAT" the variables are WIRECHAT_URL, WIRECHAT_HANDSHAKE_TIMEOUT and
+// WIRECHAT_REST_BASE_URL. See LoadConfig for value formats.
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	values := make(map[string]any)
+	for _, key := range configKeys() {
+		if v, ok := os.LookupEnv(prefix + strings.ToUpper(key)); ok {
+			values[key] = v
+		}
+	}
+	cfg := DefaultConfig()
+	err := applyConfig(&cfg, values, "environment")
+	return cfg, err
+}
+
+// LoadConfig layers a JSON (.json) or TOML (.toml) file over DefaultConfig.
+// Keys are Config field names in snake case (url, rest_base_url,
+// max_reconnect_tries, ...). Durations are Go duration strings ("10s"),
+// write_queue_policy and write_fairness take their String names, codec takes
+// "json" or "cbor" and transport takes "websocket", "auto" (WebSocket with
+// long-polling fallback), "longpoll" or "socket". token_file reads Token from
+// a file. Metrics, Tracer and MessageStore cannot be loaded and must be set in
+// code. Unknown keys are reported as errors.
+//
+// TOML files are read by a built-in parser that supports the subset config
+// files need: comments, bare and double-quoted keys, basic and literal
+// strings, integers, booleans, single-line arrays of those values, [table]
+// headers and [[array of tables]] headers. Dotted keys and table names,
+// multi-line strings and arrays, nested arrays, inline tables, floats and
+// dates fail with an "unsupported TOML construct" error naming the line.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, WrapError(ErrorInvalidConfig, "read config file", err)
+	}
+
+	var values map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	case ".toml":
+		values, err = parseTOML(data)
+	default:
+		return Config{}, NewError(ErrorInvalidConfig, fmt.Sprintf("unsupported config format %q", ext))
+	}
+	if err != nil {
+		return Config{}, WrapError(ErrorInvalidConfig, "parse config file", err)
+	}
+
+	cfg := DefaultConfig()
+	err = applyConfig(&cfg, values, path)
+	return cfg, err
+}
+
+// configField describes a Config field that can be loaded.
+type configField struct {
+	key   string
+	index int
+}
+
+// loadableFields lists Config fields in declaration order. Interface fields
+// other than Transport and Codec cannot be expressed in text and are skipped.
+func loadableFields() []configField {
+	t := reflect.TypeFor[Config]()
+	var fields []configField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Name == "Metrics" || f.Name == "Tracer" || f.Name == "MessageStore" {
+			continue
+		}
+		fields = append(fields, configField{key: snakeCase(f.Name), index: i})
+	}
+	return fields
+}
+
+// configKeys returns every key accepted by the loaders.
+func configKeys() []string {
+	var keys []string
+	for _, f := range loadableFields() {
+		keys = append(keys, f.key)
+	}
+	return append(keys, tokenFileKey)
+}
+
+// applyConfig sets cfg fields from raw values and joins every problem found.
+func applyConfig(cfg *Config, values map[string]any, source string) error {
+	fields := make(map[string]int)
+	for _, f := range loadableFields() {
+		fields[f.key] = f.index
+	}
+
+	var errs []error
+	fail := func(key string, err error) {
+		errs = append(errs, WrapError(ErrorInvalidConfig, fmt.Sprintf("%s: %s", source, key), err))
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for _, key := range sortedKeys(values) {
+		raw := values[key]
+		if key == tokenFileKey {
+			continue
+		}
+		i, ok := fields[key]
+		if !ok {
+			fail(key, errors.New("unknown setting"))
+			continue
+		}
+		if err := setConfigField(v.Field(i), raw); err != nil {
+			fail(key, err)
+		}
+	}
+
+	// The token file wins over an inline token so secrets can be rotated on disk
+	if raw, ok := values[tokenFileKey]; ok {
+		path, err := asString(raw)
+		if err == nil {
+			var data []byte
+			data, err = os.ReadFile(path)
+			cfg.Token = strings.TrimSpace(string(data))
+		}
+		if err != nil {
+			fail(tokenFileKey, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setConfigField converts a raw string, number, bool, list or table into the field type.
+func setConfigField(f reflect.Value, raw any) error {
+	switch f.Type() {
+	case reflect.TypeFor[time.Duration]():
+		s, err := asString(raw)
+		if err != nil {
+			return err
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	case reflect.TypeFor[OverflowPolicy]():
+		return setEnum(f, raw, []fmt.Stringer{OverflowBlock, OverflowDropOldest, OverflowDropNewest, OverflowError})
+	case reflect.TypeFor[Fairness]():
+		return setEnum(f, raw, []fmt.Stringer{FairnessStrict, FairnessWeighted})
+	case reflect.TypeFor[codec.Codec]():
+		s, err := asString(raw)
+		if err != nil {
+			return err
+		}
+		cd, ok := codec.ByName(s)
+		if !ok {
+			cd, ok = codec.ByName("wirechat." + s)
+		}
+		if !ok {
+			return fmt.Errorf("unknown codec %q", s)
+		}
+		f.Set(reflect.ValueOf(&cd).Elem())
+		return nil
+	case reflect.TypeFor[transport.Transport]():
+		s, err := asString(raw)
+		if err != nil {
+			return err
+		}
+		t, err := transportByName(s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(&t).Elem())
+		return nil
+	case reflect.TypeFor[[]Endpoint]():
+		eps, err := parseEndpoints(raw)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(eps))
+		return nil
+	case reflect.TypeFor[map[Lane]int]():
+		weights, err := parseLaneWeights(raw)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(weights))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		s, err := asString(raw)
+		if err != nil {
+			return err
+		}
+		f.SetString(s)
+	case reflect.Int:
+		n, err := asInt(raw)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := asBool(raw)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func setEnum(f reflect.Value, raw any, values []fmt.Stringer) error {
+	s, err := asString(raw)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, v := range values {
+		if v.String() == s {
+			f.Set(reflect.ValueOf(v))
+			return nil
+		}
+		names = append(names, v.String())
+	}
+	return fmt.Errorf("unknown value %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+func transportByName(name string) (transport.Transport, error) {
+	switch name {
+	case "auto":
+		return autoTransport(), nil
+	case "websocket":
+		return transport.WebSocket{}, nil
+	case "longpoll":
+		return transport.LongPoll{}, nil
+	case "socket":
+		return transport.Socket{}, nil
+	}
+	return nil, fmt.Errorf("unknown transport %q (want auto, websocket, longpoll or socket)", name)
+}
+
+// parseEndpoints accepts a list of tables with url and rest_base_url, or a
+// comma-separated string of "url" or "url|rest_base_url" entries.
+func parseEndpoints(raw any) ([]Endpoint, error) {
+	if s, ok := raw.(string); ok {
+		var eps []Endpoint
+		for _, entry := range strings.Split(s, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			wsURL, restURL, _ := strings.Cut(entry, "|")
+			eps = append(eps, Endpoint{URL: strings.TrimSpace(wsURL), RESTBaseURL: strings.TrimSpace(restURL)})
+		}
+		return eps, nil
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected list of endpoints, got %T", raw)
+	}
+	eps := make([]Endpoint, 0, len(list))
+	for i, item := range list {
+		table, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("endpoint %d: expected table, got %T", i, item)
+		}
+		var ep Endpoint
+		for key, value := range table {
+			s, err := asString(value)
+			if err != nil {
+				return nil, fmt.Errorf("endpoint %d: %s: %w", i, key, err)
+			}
+			switch key {
+			case "url":
+				ep.URL = s
+			case "rest_base_url":
+				ep.RESTBaseURL = s
+			default:
+				return nil, fmt.Errorf("endpoint %d: unknown setting %q", i, key)
+			}
+		}
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+// parseLaneWeights accepts a table of lane name to weight, or a string such
+// as "control=4,message=2".
+func parseLaneWeights(raw any) (map[Lane]int, error) {
+	table, ok := raw.(map[string]any)
+	if s, isString := raw.(string); isString {
+		table, ok = make(map[string]any), true
+		for _, entry := range strings.Split(s, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			name, weight, found := strings.Cut(entry, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid lane weight %q (want lane=weight)", entry)
+			}
+			table[strings.TrimSpace(name)] = strings.TrimSpace(weight)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected table of lane weights, got %T", raw)
+	}
+
+	weights := defaultLaneWeights()
+	for name, value := range table {
+		lane, found := laneByName(name)
+		if !found {
+			return nil, fmt.Errorf("unknown lane %q", name)
+		}
+		n, err := asInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("lane %s: %w", name, err)
+		}
+		weights[lane] = n
+	}
+	return weights, nil
+}
+
+func laneByName(name string) (Lane, bool) {
+	for l := range laneCount {
+		if l.String() == name {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+func asString(raw any) (string, error) {
+	if s, ok := raw.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("expected string, got %T", raw)
+}
+
+func asInt(raw any) (int, error) {
+	switch v := raw.(type) {
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("expected integer, got %v", v)
+		}
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("expected integer, got %T", raw)
+}
+
+func asBool(raw any) (bool, error) {
+	switch v := raw.(type) {
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("expected bool, got %T", raw)
+}
+
+// snakeCase converts a Go field name to snake case, keeping acronyms
+// together: RESTBaseURL becomes rest_base_url.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			acronymEnd := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+package wirechat
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := dir + "/token"
+	if err := os.WriteFile(tokenPath, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WIRECHAT_URL", "wss://env.example/ws")
+	t.Setenv("WIRECHAT_HANDSHAKE_TIMEOUT", "3s")
+	t.Setenv("WIRECHAT_AUTO_RECONNECT", "true")
+	t.Setenv("WIRECHAT_ENDPOINTS", "wss://a/ws|https://a/api, wss://b/ws")
+	t.Setenv("WIRECHAT_TOKEN", "inline")
+	t.Setenv("WIRECHAT_TOKEN_FILE", tokenPath)
+	cfg, err := ConfigFromEnv("WIRECHAT")
+	if err != nil {
+		t.Fatalf("env: %v", err)
+	}
+	if cfg.URL != "wss://env.example/ws" || cfg.HandshakeTimeout != 3*time.Second || !cfg.AutoReconnect {
+		t.Fatalf("env values not applied: %+v", cfg)
+	}
+	if cfg.Token != "secret" {
+		t.Fatalf("token file should win, got %q", cfg.Token)
+	}
+	if len(cfg.Endpoints) != 2 || cfg.Endpoints[0].RESTBaseURL != "https://a/api" || cfg.Endpoints[1].URL != "wss://b/ws" {
+		t.Fatalf("endpoints: %+v", cfg.Endpoints)
+	}
+	if cfg.WriteTimeout != DefaultConfig().WriteTimeout {
+		t.Fatalf("unset fields should keep defaults")
+	}
+
+	tomlPath := dir + "/wirechat.toml"
+	tomlData := `# client settings
+url = "ws://localhost:8080/ws" # inline comment
+max_reconnect_tries = 5
+write_queue_policy = "drop_oldest"
+codec = "cbor"
+
+[lane_weights]
+control = 8
+
+[[endpoints]]
+url = "ws://primary/ws"
+rest_base_url = "http://primary/api"
+`
+	if err := os.WriteFile(tomlPath, []byte(tomlData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(tomlPath)
+	if err != nil {
+		t.Fatalf("toml: %v", err)
+	}
+	if cfg.URL != "ws://localhost:8080/ws" || cfg.MaxReconnectTries != 5 || cfg.WriteQueuePolicy != OverflowDropOldest {
+		t.Fatalf("toml values not applied: %+v", cfg)
+	}
+	if cfg.Codec != codec.CBOR || cfg.LaneWeights[LaneControl] != 8 || len(cfg.Endpoints) != 1 {
+		t.Fatalf("toml tables not applied: %+v", cfg)
+	}
+
+	// Valid TOML outside the supported subset is named, not misparsed
+	for construct, data := range map[string]string{
+		"dotted key":        "client.url = \"ws://x/ws\"",
+		"dotted table":      "[client.rest]",
+		"multi-line string": "token = \"\"\"\nsecret\"\"\"",
+		"multi-line array":  "endpoints = [\n]",
+		"nested array":      "urls = [[\"a\"], [\"b\"]]",
+		"inline table":      "lane_weights = { control = 8 }",
+		"float":             "max_reconnect_tries = 1.5",
+		"date-time":         "issued = 2024-05-27T07:32:00Z",
+	} {
+		if err := os.WriteFile(tomlPath, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadConfig(tomlPath)
+		if !errors.Is(err, errUnsupportedTOML) || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("%s: expected unsupported TOML construct on line 1, got %v", construct, err)
+		}
+	}
+
+	jsonPath := dir + "/wirechat.json"
+	if err := os.WriteFile(jsonPath, []byte(`{"url": "ws://x/ws", "heartbeat_interval": "nope", "colour": "blue"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(jsonPath)
+	if !errors.Is(err, NewError(ErrorInvalidConfig, "")) {
+		t.Fatalf("expected invalid_config, got %v", err)
+	}
+	for _, key := range []string{"heartbeat_interval", "colour"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("missing %s problem in %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package wirechat
+
+import (
+	"context"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+)
+
+// RegisterEvent registers a typed handler for a custom server event.
+// Outbound.Data is decoded into T with UnmarshalData; decode failures are
+// reported to OnError as ErrorSerialization. Registering a built-in event
+// name replaces its typed handler. Register handlers before calling Connect.
+// c is usually a *Client or a ChatClient.
+func RegisterEvent[T any](c EventHandler, event string, fn func(T)) {
+	c.HandleEvent(event, func(out Outbound) error {
+		var ev T
+		if err := UnmarshalData(out.Data, &ev); err != nil {
+			return err
+		}
+		fn(ev)
+		return nil
+	})
+}
+
+// SendCommand sends an arbitrary inbound frame, e.g. for server extensions
+// the SDK does not model. payload is encoded as the frame's data.
+// c is usually a *Client or a ChatClient.
+func SendCommand(c CommandSender, ctx context.Context, frameType string, payload any) error {
+	return c.SendFrame(ctx, frameType, payload)
+}
+
+// HandleEvent registers an untyped handler for a custom server event. Prefer
+// RegisterEvent, which decodes the data into a typed value.
+func (c *Client) HandleEvent(event string, fn func(Outbound) error) {
+	c.dispatcher.setCustom(event, fn)
+}
+
+// SendFrame sends an arbitrary inbound frame; see SendCommand.
+func (c *Client) SendFrame(ctx context.Context, frameType string, payload any) (err error) {
+	ctx, span := c.tracer.Start(ctx, "wirechat.command", trace.String(trace.AttrFrameType, frameType))
+	defer func() { endSpan(span, err) }()
+
+	if frameType == "" {
+		return NewError(ErrorBadRequest, "empty command type")
+	}
+	return c.send(ctx, Inbound{Type: frameType, Data: payload})
+}
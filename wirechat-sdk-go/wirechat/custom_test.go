@@ -0,0 +1,32 @@
+package wirechat
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRegisterEvent(t *testing.T) {
+	type moderation struct {
+		Target string `json:"target"`
+		Action string `json:"action"`
+	}
+
+	cfg := DefaultConfig()
+	c := NewClient(&cfg)
+
+	var got moderation
+	var errGot error
+	RegisterEvent(c, "moderation", func(ev moderation) { got = ev })
+	c.OnError(func(err error) { errGot = err })
+
+	c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: "moderation", Data: json.RawMessage(`{"target":"bob","action":"mute"}`)})
+	if got.Target != "bob" || got.Action != "mute" {
+		t.Fatalf("unexpected event: %+v", got)
+	}
+
+	c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: "moderation", Data: json.RawMessage(`{"target":1}`)})
+	if !errors.Is(errGot, NewError(ErrorSerialization, "")) {
+		t.Fatalf("expected serialization error, got %v", errGot)
+	}
+}
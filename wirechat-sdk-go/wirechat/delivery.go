@@ -0,0 +1,199 @@
+package wirechat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// DeliveryStatus represents the delivery progress of an outgoing message.
+type DeliveryStatus int
+
+const (
+	// DeliveryPending means the message is waiting in the write queue.
+	DeliveryPending DeliveryStatus = iota
+
+	// DeliveryQueued means the message is held in the buffer until the client reconnects.
+	DeliveryQueued
+
+	// DeliverySent means the message was written to the socket.
+	DeliverySent
+
+	// DeliveryConfirmed means the server echoed the message back with an ID.
+	DeliveryConfirmed
+
+	// DeliveryFailed means the message will not be delivered.
+	DeliveryFailed
+)
+
+// String returns the string representation of a DeliveryStatus.
+func (s DeliveryStatus) String() string {
+	switch s {
+	case DeliveryPending:
+		return "pending"
+	case DeliveryQueued:
+		return "queued"
+	case DeliverySent:
+		return "sent"
+	case DeliveryConfirmed:
+		return "confirmed"
+	case DeliveryFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// deliveryUpdates is the capacity of Delivery.Updates. It does not depend on
+// the config, so raising MaxResendAttempts with UpdateConfig cannot outgrow it.
+const deliveryUpdates = 32
+
+// Delivery tracks the status of a single outgoing message.
+// LocalID is generated by the client and lets UIs render optimistic messages
+// before the server assigns an ID.
+type Delivery struct {
+	LocalID string
+	Room    string
+	Text    string
+
+	mu      sync.Mutex
+	status  DeliveryStatus
+	id      int64
+	err     *WirechatError
+	updates chan DeliveryStatus
+	done    chan struct{}
+}
+
+func newDelivery(room, text string) *Delivery {
+	d := &Delivery{
+		LocalID: newLocalID(),
+		Room:    room,
+		Text:    text,
+		status:  DeliveryPending,
+		updates: make(chan DeliveryStatus, deliveryUpdates),
+		done:    make(chan struct{}),
+	}
+	d.updates <- DeliveryPending
+	return d
+}
+
+// ConfirmedDelivery returns a Delivery that is already confirmed with id.
+// It lets fakes and mocks of ChatClient implement SendWithDelivery.
+func ConfirmedDelivery(room, text string, id int64) *Delivery {
+	d := newDelivery(room, text)
+	d.confirm(id)
+	return d
+}
+
+// Status returns the current delivery status.
+func (d *Delivery) Status() DeliveryStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// ID returns the server-assigned message ID (0 until confirmed or for guest messages).
+func (d *Delivery) ID() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.id
+}
+
+// Err returns the error that caused the delivery to fail, if any.
+func (d *Delivery) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		return nil
+	}
+	return d.err
+}
+
+// Updates returns a channel receiving every status, starting with the initial
+// DeliveryPending. The channel is closed once the delivery is confirmed or
+// failed. Sends never block: a reader that falls more than 32 statuses behind
+// loses the oldest intermediate ones, but the final status is always received.
+func (d *Delivery) Updates() <-chan DeliveryStatus { return d.updates }
+
+// Done returns a channel closed once the delivery is confirmed or failed.
+func (d *Delivery) Done() <-chan struct{} { return d.done }
+
+// Wait blocks until the delivery is confirmed or failed and returns the failure error.
+func (d *Delivery) Wait(ctx context.Context) error {
+	select {
+	case <-d.done:
+		return d.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Delivery) setStatus(status DeliveryStatus) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setStatusLocked(status)
+}
+
+func (d *Delivery) setStatusLocked(status DeliveryStatus) {
+	if d.isFinalLocked() || d.status == status {
+		return
+	}
+	d.status = status
+	for sent := false; !sent; {
+		select {
+		case d.updates <- status:
+			sent = true
+		default:
+			// The reader fell behind: drop the oldest status to make room
+			select {
+			case <-d.updates:
+			default:
+			}
+		}
+	}
+	if d.isFinalLocked() {
+		close(d.updates)
+		close(d.done)
+	}
+}
+
+func (d *Delivery) confirm(id int64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.isFinalLocked() {
+		return
+	}
+	d.id = id
+	d.setStatusLocked(DeliveryConfirmed)
+}
+
+func (d *Delivery) fail(err *WirechatError) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.isFinalLocked() {
+		return
+	}
+	d.err = err
+	d.setStatusLocked(DeliveryFailed)
+}
+
+func (d *Delivery) isFinalLocked() bool {
+	return d.status == DeliveryConfirmed || d.status == DeliveryFailed
+}
+
+// newLocalID generates a random client-side message identifier.
+func newLocalID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
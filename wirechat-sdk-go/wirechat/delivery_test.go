@@ -0,0 +1,110 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliveryStatus(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BufferMessages = true
+	c := NewClient(&cfg)
+
+	d, err := c.SendWithDelivery(context.Background(), "general", "hi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.LocalID == "" || d.Status() != DeliveryQueued {
+		t.Fatalf("expected queued delivery with local ID, got %s %q", d.Status(), d.LocalID)
+	}
+
+	c.resend.track(c.messageBuffer[0])
+	raw, _ := json.Marshal(MessageEvent{ID: 42, Room: "general", User: "alice", Text: "hi"})
+	c.handleInflight(context.Background(), Outbound{Type: outboundEvent, Event: eventMessage, Data: raw})
+
+	if err := d.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected delivery error: %v", err)
+	}
+	if d.Status() != DeliveryConfirmed || d.ID() != 42 {
+		t.Fatalf("expected confirmed delivery with ID 42, got %s %d", d.Status(), d.ID())
+	}
+	var got []string
+	for status := range d.Updates() {
+		got = append(got, status.String())
+	}
+	if strings.Join(got, ",") != "pending,queued,confirmed" {
+		t.Fatalf("unexpected updates: %v", got)
+	}
+
+	// Many resends never block and still end with the final status
+	d = newDelivery("general", "retried")
+	for range 2 * deliveryUpdates {
+		d.setStatus(DeliverySent)
+		d.setStatus(DeliveryPending)
+	}
+	d.fail(NewError(ErrorTimeout, "no confirmation from server"))
+	var last DeliveryStatus
+	n := 0
+	for status := range d.Updates() {
+		last = status
+		n++
+	}
+	if last != DeliveryFailed || n != deliveryUpdates {
+		t.Fatalf("expected %d updates ending in failed, got %d ending in %s", deliveryUpdates, n, last)
+	}
+}
+
+func TestDeliveryFailures(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BufferMessages = true
+	c := NewClient(&cfg)
+	msg := func(text string) (outgoing, *Delivery) {
+		d := newDelivery("general", text)
+		return outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: text}}, delivery: d}, d
+	}
+	expectFailed := func(name string, d *Delivery, code ErrorCode) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := d.Wait(ctx); !errors.Is(err, NewError(code, "")) {
+			t.Fatalf("%s: expected %s failure, got %s (%v)", name, code, d.Status(), err)
+		}
+	}
+
+	// Messages in flight fail when the connection is lost
+	out, lost := msg("lost")
+	c.resend.track(out)
+	c.resetResend(NewError(ErrorDisconnected, "connection lost"))
+	expectFailed("reset", lost, ErrorDisconnected)
+
+	// Unconfirmed messages time out
+	out, stale := msg("stale")
+	c.resend.track(out)
+	c.resend.inflight[0].sentAt = time.Now().Add(-2 * inflightTTL)
+	next, _ := msg("next")
+	c.resend.track(next)
+	expectFailed("prune", stale, ErrorTimeout)
+
+	// Close fails buffered, queued and in-flight messages
+	buffered, err := c.SendWithDelivery(context.Background(), "general", "buffered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, queued := msg("queued")
+	c.queue.lane(inboundMsg) <- out
+	out, inflight := msg("inflight")
+	c.resend.track(out)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, d := range map[string]*Delivery{"buffered": buffered, "queued": queued, "inflight": inflight} {
+		expectFailed(name, d, ErrorDisconnected)
+	}
+	if len(c.messageBuffer) != 0 || len(c.queue.lane(inboundMsg)) != 0 {
+		t.Fatal("close left messages behind")
+	}
+}
@@ -8,77 +8,216 @@ type Dispatcher struct {
 	onHistory      func(HistoryEvent)
 	onError        func(error)
 	onStateChanged func(StateEvent)
+	onHeartbeat    func(HeartbeatEvent)
+	onRawFrame     func(Direction, []byte)
+	onUnknownEvent func(Outbound)
+	onBreaker      func(BreakerEvent)
+	onRoster       func(RosterEvent)
+	hooks          []eventHooks                    // SDK components observing events ahead of the callbacks
+	custom         map[string]func(Outbound) error // Handlers added with RegisterEvent
+	metrics        Metrics
+	logger         Logger
 }
 
-func (d *Dispatcher) SetOnMessage(fn func(MessageEvent))    { d.onMessage = fn }
-func (d *Dispatcher) SetOnUserJoined(fn func(UserEvent))    { d.onUserJoined = fn }
-func (d *Dispatcher) SetOnUserLeft(fn func(UserEvent))      { d.onUserLeft = fn }
-func (d *Dispatcher) SetOnHistory(fn func(HistoryEvent))    { d.onHistory = fn }
-func (d *Dispatcher) SetOnError(fn func(error))             { d.onError = fn }
-func (d *Dispatcher) SetOnStateChanged(fn func(StateEvent)) { d.onStateChanged = fn }
+func (d *Dispatcher) SetOnMessage(fn func(MessageEvent))             { d.onMessage = fn }
+func (d *Dispatcher) SetOnUserJoined(fn func(UserEvent))             { d.onUserJoined = fn }
+func (d *Dispatcher) SetOnUserLeft(fn func(UserEvent))               { d.onUserLeft = fn }
+func (d *Dispatcher) SetOnHistory(fn func(HistoryEvent))             { d.onHistory = fn }
+func (d *Dispatcher) SetOnError(fn func(error))                      { d.onError = fn }
+func (d *Dispatcher) SetOnStateChanged(fn func(StateEvent))          { d.onStateChanged = fn }
+func (d *Dispatcher) SetOnHeartbeat(fn func(HeartbeatEvent))         { d.onHeartbeat = fn }
+func (d *Dispatcher) SetOnRawFrame(fn func(Direction, []byte))       { d.onRawFrame = fn }
+func (d *Dispatcher) SetOnUnknownEvent(fn func(Outbound))            { d.onUnknownEvent = fn }
+func (d *Dispatcher) SetOnBreakerStateChanged(fn func(BreakerEvent)) { d.onBreaker = fn }
+func (d *Dispatcher) SetOnRosterChanged(fn func(RosterEvent))        { d.onRoster = fn }
+
+// eventHooks lets SDK components such as the roster observe decoded events
+// before the user callbacks run, without taking the callbacks over. Nil
+// fields are skipped.
+type eventHooks struct {
+	message    func(MessageEvent)
+	userJoined func(UserEvent)
+	userLeft   func(UserEvent)
+	history    func(HistoryEvent)
+	state      func(StateEvent)
+}
+
+// addHooks registers an observer. Add hooks before calling Connect.
+func (d *Dispatcher) addHooks(h eventHooks) {
+	d.hooks = append(d.hooks, h)
+}
 
 func (d *Dispatcher) Dispatch(out Outbound) {
-	if out.Type == outboundError && out.Error != nil && d.onError != nil {
+	if out.Type == outboundError && out.Error != nil {
 		// Convert protocol error to WirechatError
-		d.onError(FromProtocolError(out.Error))
+		d.log().Warn("protocol error", map[string]any{"code": out.Error.Code, "error": out.Error.Msg})
+		d.fireError(FromProtocolError(out.Error))
+		return
+	}
+	if fn, ok := d.custom[out.Event]; ok && out.Type == outboundEvent {
+		if err := fn(out); err != nil {
+			d.malformed(out.Event, err)
+		}
+		return
+	}
+	if !isKnownEvent(out) {
+		// Hand unrecognized events over before any decoding
+		d.log().Warn("unknown event", map[string]any{"type": out.Type, "event": out.Event})
+		if d.onUnknownEvent != nil {
+			d.onUnknownEvent(out)
+		}
 		return
 	}
 	switch out.Event {
 	case eventMessage:
-		if d.onMessage == nil {
+		if d.onMessage == nil && len(d.hooks) == 0 {
 			return
 		}
 		var ev MessageEvent
 		if err := UnmarshalData(out.Data, &ev); err != nil {
-			d.fireError(WrapError(ErrorSerialization, "failed to unmarshal message event", err))
+			d.malformed("message", err)
 			return
 		}
-		d.onMessage(ev)
+		for _, h := range d.hooks {
+			if h.message != nil {
+				h.message(ev)
+			}
+		}
+		if d.onMessage != nil {
+			d.onMessage(ev)
+		}
 	case eventUserJoined:
-		if d.onUserJoined == nil {
+		if d.onUserJoined == nil && len(d.hooks) == 0 {
 			return
 		}
 		var ev UserEvent
 		if err := UnmarshalData(out.Data, &ev); err != nil {
-			d.fireError(WrapError(ErrorSerialization, "failed to unmarshal user_joined event", err))
+			d.malformed("user_joined", err)
 			return
 		}
-		d.onUserJoined(ev)
+		for _, h := range d.hooks {
+			if h.userJoined != nil {
+				h.userJoined(ev)
+			}
+		}
+		if d.onUserJoined != nil {
+			d.onUserJoined(ev)
+		}
 	case eventUserLeft:
-		if d.onUserLeft == nil {
+		if d.onUserLeft == nil && len(d.hooks) == 0 {
 			return
 		}
 		var ev UserEvent
 		if err := UnmarshalData(out.Data, &ev); err != nil {
-			d.fireError(WrapError(ErrorSerialization, "failed to unmarshal user_left event", err))
+			d.malformed("user_left", err)
 			return
 		}
-		d.onUserLeft(ev)
+		for _, h := range d.hooks {
+			if h.userLeft != nil {
+				h.userLeft(ev)
+			}
+		}
+		if d.onUserLeft != nil {
+			d.onUserLeft(ev)
+		}
 	case eventHistory:
-		if d.onHistory == nil {
+		if d.onHistory == nil && len(d.hooks) == 0 {
 			return
 		}
 		var ev HistoryEvent
 		if err := UnmarshalData(out.Data, &ev); err != nil {
-			d.fireError(WrapError(ErrorSerialization, "failed to unmarshal history event", err))
+			d.malformed("history", err)
 			return
 		}
-		d.onHistory(ev)
+		for _, h := range d.hooks {
+			if h.history != nil {
+				h.history(ev)
+			}
+		}
+		if d.onHistory != nil {
+			d.onHistory(ev)
+		}
+	}
+}
+
+// setCustom registers a decoder-backed handler for an event name.
+func (d *Dispatcher) setCustom(event string, fn func(Outbound) error) {
+	if d.custom == nil {
+		d.custom = make(map[string]func(Outbound) error)
+	}
+	d.custom[event] = fn
+}
+
+// isKnownEvent reports whether the dispatcher has a typed handler for out.
+func isKnownEvent(out Outbound) bool {
+	if out.Type != outboundEvent {
+		return false
 	}
+	switch out.Event {
+	case eventMessage, eventUserJoined, eventUserLeft, eventHistory:
+		return true
+	default:
+		return false
+	}
+}
+
+// malformed reports an event whose data could not be decoded.
+func (d *Dispatcher) malformed(event string, err error) {
+	d.log().Error("malformed event", map[string]any{"event": event, "error": err.Error()})
+	d.fireError(WrapError(ErrorSerialization, "failed to unmarshal "+event+" event", err))
+}
+
+// log returns the configured logger or a no-op logger for a zero Dispatcher.
+func (d *Dispatcher) log() Logger {
+	if d.logger == nil {
+		return noopLogger{}
+	}
+	return d.logger
 }
 
 func (d *Dispatcher) fireError(err error) {
-	if d.onError != nil && err != nil {
+	if err == nil {
+		return
+	}
+	if d.metrics != nil {
+		d.metrics.Error(errorCode(err))
+	}
+	if d.onError != nil {
 		d.onError(err)
 	}
 }
 
-func (d *Dispatcher) fireStateChange(oldState, newState ConnectionState, err error) {
+func (d *Dispatcher) fireStateChange(ev StateEvent) {
+	for _, h := range d.hooks {
+		if h.state != nil {
+			h.state(ev)
+		}
+	}
 	if d.onStateChanged != nil {
-		d.onStateChanged(StateEvent{
-			OldState: oldState,
-			NewState: newState,
-			Error:    err,
-		})
+		d.onStateChanged(ev)
+	}
+}
+
+func (d *Dispatcher) fireBreakerChange(ev BreakerEvent) {
+	if d.onBreaker != nil {
+		d.onBreaker(ev)
+	}
+}
+
+func (d *Dispatcher) fireRosterChange(ev RosterEvent) {
+	if d.onRoster != nil {
+		d.onRoster(ev)
+	}
+}
+
+func (d *Dispatcher) fireHeartbeat(ev HeartbeatEvent) {
+	if d.onHeartbeat != nil {
+		d.onHeartbeat(ev)
+	}
+}
+
+func (d *Dispatcher) fireRawFrame(dir Direction, data []byte) {
+	if d.onRawFrame != nil {
+		d.onRawFrame(dir, data)
 	}
 }
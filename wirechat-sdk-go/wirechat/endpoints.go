@@ -0,0 +1,190 @@
+package wirechat
+
+import (
+	"sync"
+	"time"
+)
+
+// Endpoint is a WireChat server the client can connect to.
+type Endpoint struct {
+	URL         string // WebSocket URL
+	RESTBaseURL string // REST API base URL paired with URL (optional)
+}
+
+// endpointLatencyWeight is the smoothing factor applied to new latency samples.
+const endpointLatencyWeight = 0.3
+
+// endpointStats tracks the health of a single endpoint.
+type endpointStats struct {
+	Endpoint
+	failures    int           // Consecutive failures
+	latency     time.Duration // Smoothed dial and heartbeat round-trip time (0 = unknown)
+	bannedUntil time.Time
+}
+
+// endpointPool ranks endpoints by health and latency and bans the ones that keep failing.
+type endpointPool struct {
+	mu          sync.Mutex
+	stats       []*endpointStats
+	maxFailures int
+	banDuration time.Duration
+}
+
+// newEndpointPool builds the pool from Config.Endpoints, falling back to URL and RESTBaseURL.
+func newEndpointPool(cfg *Config) *endpointPool {
+	eps := cfg.Endpoints
+	if len(eps) == 0 && cfg.URL != "" {
+		eps = []Endpoint{{URL: cfg.URL, RESTBaseURL: cfg.RESTBaseURL}}
+	}
+	p := &endpointPool{maxFailures: cfg.EndpointMaxFailures, banDuration: cfg.EndpointBanDuration}
+	for _, ep := range eps {
+		p.stats = append(p.stats, &endpointStats{Endpoint: ep})
+	}
+	return p
+}
+
+func (p *endpointPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.stats)
+}
+
+// reset replaces the endpoint list and ban settings after a config update.
+// Endpoints that are still configured keep their health and latency.
+func (p *endpointPool) reset(cfg *Config) {
+	next := newEndpointPool(cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, s := range next.stats {
+		if old := p.findLocked(s.Endpoint); old != nil {
+			next.stats[i] = old
+		}
+	}
+	p.stats = next.stats
+	p.maxFailures = next.maxFailures
+	p.banDuration = next.banDuration
+}
+
+// pick returns the best endpoint: not banned, fewest consecutive failures,
+// then lowest known latency, then configuration order. If every endpoint is
+// banned the one whose ban expires first is returned.
+func (p *endpointPool) pick() Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *endpointStats
+	for _, s := range p.stats {
+		if best == nil || s.betterThan(best, now) {
+			best = s
+		}
+	}
+	if best == nil {
+		return Endpoint{}
+	}
+	return best.Endpoint
+}
+
+func (s *endpointStats) betterThan(o *endpointStats, now time.Time) bool {
+	banned, otherBanned := s.bannedUntil.After(now), o.bannedUntil.After(now)
+	if banned != otherBanned {
+		return !banned
+	}
+	if banned {
+		return s.bannedUntil.Before(o.bannedUntil)
+	}
+	if s.failures != o.failures {
+		return s.failures < o.failures
+	}
+	if (s.latency == 0) != (o.latency == 0) {
+		return s.latency != 0
+	}
+	return s.latency < o.latency
+}
+
+// success records a successful connection and its dial latency.
+func (p *endpointPool) success(ep Endpoint, rtt time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.findLocked(ep); s != nil {
+		s.failures = 0
+		s.observeLocked(rtt)
+	}
+}
+
+// failure records a failed dial or handshake. After maxFailures consecutive
+// failures the endpoint is banned for banDuration. A connection lost after
+// the handshake is not a failure: the server was reachable, and counting it
+// would ban endpoints that merely restart.
+func (p *endpointPool) failure(ep Endpoint) (banned bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.findLocked(ep)
+	if s == nil {
+		return false
+	}
+	s.failures++
+	if p.maxFailures > 0 && s.failures >= p.maxFailures && p.banDuration > 0 {
+		s.failures = 0
+		s.bannedUntil = time.Now().Add(p.banDuration)
+		return true
+	}
+	return false
+}
+
+// observe records a latency sample, e.g. from a heartbeat.
+func (p *endpointPool) observe(ep Endpoint, rtt time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.findLocked(ep); s != nil {
+		s.observeLocked(rtt)
+	}
+}
+
+func (s *endpointStats) observeLocked(rtt time.Duration) {
+	if rtt <= 0 {
+		return
+	}
+	if s.latency == 0 {
+		s.latency = rtt
+		return
+	}
+	s.latency = time.Duration(endpointLatencyWeight*float64(rtt) + (1-endpointLatencyWeight)*float64(s.latency))
+}
+
+func (p *endpointPool) findLocked(ep Endpoint) *endpointStats {
+	for _, s := range p.stats {
+		if s.Endpoint == ep {
+			return s
+		}
+	}
+	return nil
+}
+
+// restBaseURL returns the REST base URL paired with the first endpoint.
+func (cfg *Config) restBaseURL() string {
+	if len(cfg.Endpoints) > 0 {
+		return cfg.endpointRESTURL(cfg.Endpoints[0])
+	}
+	return cfg.RESTBaseURL
+}
+
+// endpointRESTURL returns the REST base URL of ep, or RESTBaseURL for an
+// endpoint without one.
+func (cfg *Config) endpointRESTURL(ep Endpoint) string {
+	if ep.RESTBaseURL != "" {
+		return ep.RESTBaseURL
+	}
+	return cfg.RESTBaseURL
+}
+
+// Endpoint returns the endpoint of the current or last connection.
+func (c *Client) Endpoint() Endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.endpoint
+}
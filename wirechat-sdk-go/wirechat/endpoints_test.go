@@ -0,0 +1,125 @@
+package wirechat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+func TestEndpointFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.CloseNow()
+		for {
+			if _, _, err := ws.Read(r.Context()); err != nil {
+				return
+			}
+		}
+	}))
+	defer live.Close()
+
+	cfg := DefaultConfig()
+	cfg.Transport = transport.WebSocket{}
+	cfg.EndpointMaxFailures = 1
+	cfg.Endpoints = []Endpoint{
+		{URL: "ws" + strings.TrimPrefix(dead.URL, "http"), RESTBaseURL: dead.URL + "/api"},
+		{URL: "ws" + strings.TrimPrefix(live.URL, "http"), RESTBaseURL: live.URL + "/api"},
+	}
+	c := NewClient(&cfg)
+
+	var endpoint string
+	c.OnStateChanged(func(ev StateEvent) {
+		if ev.NewState == StateConnected {
+			endpoint = ev.Endpoint
+		}
+	})
+
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	if endpoint != cfg.Endpoints[1].URL {
+		t.Fatalf("expected failover to %s, got %q", cfg.Endpoints[1].URL, endpoint)
+	}
+	if c.REST.BaseURL() != cfg.Endpoints[1].RESTBaseURL {
+		t.Fatalf("REST not paired with active endpoint: %s", c.REST.BaseURL())
+	}
+	// The dead endpoint is banned, so the live one stays preferred
+	if ep := c.endpoints.pick(); ep != cfg.Endpoints[1] {
+		t.Fatalf("expected banned endpoint to be skipped, got %+v", ep)
+	}
+}
+
+func TestEndpointRESTFallback(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	drop := make(chan struct{})
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		<-drop
+		ws.CloseNow()
+	}))
+	defer live.Close()
+
+	cfg := DefaultConfig()
+	cfg.Transport = transport.WebSocket{}
+	cfg.RESTBaseURL = live.URL + "/api"
+	cfg.Endpoints = []Endpoint{
+		{URL: "ws" + strings.TrimPrefix(dead.URL, "http"), RESTBaseURL: dead.URL + "/api"},
+		{URL: "ws" + strings.TrimPrefix(live.URL, "http")},
+	}
+	c := NewClient(&cfg)
+
+	disconnected := make(chan struct{}, 1)
+	c.OnStateChanged(func(ev StateEvent) {
+		if ev.NewState == StateDisconnected {
+			disconnected <- struct{}{}
+		}
+	})
+
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	// An endpoint without its own REST URL falls back to Config.RESTBaseURL
+	if c.REST.BaseURL() != cfg.RESTBaseURL {
+		t.Fatalf("expected REST fallback to %s, got %s", cfg.RESTBaseURL, c.REST.BaseURL())
+	}
+
+	// A connection lost after the handshake does not count against the endpoint
+	close(drop)
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection loss not detected")
+	}
+	c.endpoints.mu.Lock()
+	failures := c.endpoints.findLocked(cfg.Endpoints[1]).failures
+	c.endpoints.mu.Unlock()
+	if failures != 0 {
+		t.Fatalf("expected no failures for the live endpoint, got %d", failures)
+	}
+
+	// Without a fallback the endpoint would keep REST on the previous server
+	cfg.RESTBaseURL = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "Endpoints[1].RESTBaseURL") {
+		t.Fatalf("expected RESTBaseURL error for Endpoints[1], got %v", err)
+	}
+}
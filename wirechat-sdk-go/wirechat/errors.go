@@ -29,6 +29,9 @@ const (
 	ErrorInvalidConfig
 	ErrorNotConnected
 	ErrorSerialization
+	ErrorQueueFull
+	ErrorRejected
+	ErrorCircuitOpen
 )
 
 // String returns the string representation of an ErrorCode.
@@ -68,6 +71,12 @@ func (e ErrorCode) String() string {
 		return "not_connected"
 	case ErrorSerialization:
 		return "serialization_error"
+	case ErrorQueueFull:
+		return "queue_full"
+	case ErrorRejected:
+		return "rejected"
+	case ErrorCircuitOpen:
+		return "circuit_open"
 	default:
 		return fmt.Sprintf("unknown_code_%d", e)
 	}
@@ -147,6 +156,15 @@ func WrapError(code ErrorCode, message string, err error) *WirechatError {
 	}
 }
 
+// asWirechatError returns err as a WirechatError, wrapping it with ErrorUnknown if needed.
+func asWirechatError(err error) *WirechatError {
+	var we *WirechatError
+	if errors.As(err, &we) {
+		return we
+	}
+	return WrapError(ErrorUnknown, err.Error(), err)
+}
+
 // FromProtocolError converts a protocol Error to WirechatError.
 func FromProtocolError(e *Error) *WirechatError {
 	if e == nil {
@@ -182,3 +200,21 @@ func IsConnectionError(err error) bool {
 	}
 	return we.Code == ErrorConnection || we.Code == ErrorDisconnected || we.Code == ErrorTimeout
 }
+
+// MessageError reports an outgoing message that could not be delivered.
+// Payload holds the original message so the app can show a "failed to send" state.
+type MessageError struct {
+	Payload  MsgPayload
+	Attempts int
+	Err      *WirechatError
+}
+
+// Error implements the error interface.
+func (e *MessageError) Error() string {
+	return fmt.Sprintf("message to room %q failed after %d attempts: %v", e.Payload.Room, e.Attempts, e.Err)
+}
+
+// Unwrap returns the underlying WirechatError.
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
@@ -1,5 +1,7 @@
 package wirechat
 
+import "time"
+
 // MessageEvent emitted when someone sends message.
 type MessageEvent struct {
 	ID   int64  `json:"id"` // Message ID from database (0 for guest messages)
@@ -20,3 +22,33 @@ type HistoryEvent struct {
 	Room     string         `json:"room"`
 	Messages []MessageEvent `json:"messages"`
 }
+
+// HeartbeatEvent emitted after each client keepalive ping.
+type HeartbeatEvent struct {
+	Latency time.Duration // Round-trip time (0 if the ping failed)
+	Missed  int           // Consecutive missed heartbeats
+	Error   error         // Ping failure, nil on success
+}
+
+// Direction tells whether a raw frame was sent or received.
+type Direction int
+
+const (
+	// DirectionIn marks frames received from the server.
+	DirectionIn Direction = iota
+
+	// DirectionOut marks frames sent to the server.
+	DirectionOut
+)
+
+// String returns the string representation of a Direction.
+func (d Direction) String() string {
+	switch d {
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	default:
+		return "unknown"
+	}
+}
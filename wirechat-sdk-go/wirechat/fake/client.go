@@ -0,0 +1,546 @@
+// Package fake provides a recording, in-process implementation of
+// wirechat.ChatClient for unit-testing code built on the SDK.
+//
+// The fake records every call, tracks joined rooms and sent messages, and
+// lets tests trigger server events, heartbeats, breaker changes and raw frames:
+//
+//	fc := fake.New()
+//	bot := NewBot(fc) // takes a wirechat.ChatClient
+//	fc.EmitMessage(wirechat.MessageEvent{Room: "general", User: "bob", Text: "!ping"})
+//	if sent := fc.Sent(); len(sent) != 1 || sent[0].Text != "pong" { ... }
+//
+// Handlers run synchronously on the goroutine that calls an Emit method.
+package fake
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// Call is a recorded method call.
+type Call struct {
+	Method string
+	Args   []any // Arguments without the context
+}
+
+// Message is a message passed to Send.
+type Message struct {
+	Room string
+	Text string
+}
+
+// Command is a frame passed to SendFrame or wirechat.SendCommand.
+type Command struct {
+	Type    string
+	Payload any
+}
+
+// Client is a fake wirechat.ChatClient. The zero value is not usable; create it with New.
+type Client struct {
+	// REST is the fake returned by RESTAPI. Set it to nil to simulate a
+	// client without a REST base URL. JoinID, LeaveID and SendID resolve
+	// room IDs against its rooms.
+	REST *REST
+
+	mu       sync.Mutex
+	cfg      wirechat.Config
+	state    wirechat.ConnectionState
+	rooms    map[string]bool
+	sent     []Message
+	commands []Command
+	calls    []Call
+	failures map[string]error
+	nextID   int64         // Last ID assigned by SendWithDelivery
+	latency  time.Duration // Set by EmitHeartbeat
+
+	onMessage      func(wirechat.MessageEvent)
+	onUserJoined   func(wirechat.UserEvent)
+	onUserLeft     func(wirechat.UserEvent)
+	onHistory      func(wirechat.HistoryEvent)
+	onError        func(error)
+	onStateChanged func(wirechat.StateEvent)
+	onHeartbeat    func(wirechat.HeartbeatEvent)
+	onBreaker      func(wirechat.BreakerEvent)
+	onRawFrame     func(wirechat.Direction, []byte)
+	onUnknownEvent func(wirechat.Outbound)
+	custom         map[string]func(wirechat.Outbound) error
+}
+
+var _ wirechat.ChatClient = (*Client)(nil)
+
+// New returns a disconnected fake client with an empty fake REST API.
+func New() *Client {
+	return &Client{
+		REST:     NewREST(),
+		cfg:      wirechat.DefaultConfig(),
+		state:    wirechat.StateDisconnected,
+		rooms:    make(map[string]bool),
+		failures: make(map[string]error),
+		custom:   make(map[string]func(wirechat.Outbound) error),
+	}
+}
+
+// Fail makes every later call of method (e.g. "Send") return err until
+// Fail is called again with a nil error.
+func (c *Client) Fail(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.failures, method)
+		return
+	}
+	c.failures[method] = err
+}
+
+// record stores the call and returns the injected failure for it, if any.
+func (c *Client) record(method string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+	return c.failures[method]
+}
+
+// Connect moves the fake to StateConnected.
+func (c *Client) Connect(ctx context.Context) error {
+	if err := c.record("Connect"); err != nil {
+		c.SetState(wirechat.StateError, err)
+		return err
+	}
+	if c.State() == wirechat.StateConnected {
+		return wirechat.NewError(wirechat.ErrorInvalidConfig, "already connected")
+	}
+	c.SetState(wirechat.StateConnected, nil)
+	return nil
+}
+
+// Close moves the fake to StateClosed.
+func (c *Client) Close() error {
+	if err := c.record("Close"); err != nil {
+		return err
+	}
+	c.SetState(wirechat.StateClosed, nil)
+	return nil
+}
+
+// State returns the current fake connection state.
+func (c *Client) State() wirechat.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Join records room as joined. Like Client, it fails while not connected.
+func (c *Client) Join(ctx context.Context, room string) error {
+	if err := c.check("Join", room); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.rooms[room] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Leave removes room from the joined rooms.
+func (c *Client) Leave(ctx context.Context, room string) error {
+	if err := c.check("Leave", room); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.rooms, room)
+	c.mu.Unlock()
+	return nil
+}
+
+// Send records the message. It is not echoed back; use EmitMessage for that.
+func (c *Client) Send(ctx context.Context, room, text string) error {
+	if err := c.check("Send", room, text); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.sent = append(c.sent, Message{Room: room, Text: text})
+	c.mu.Unlock()
+	return nil
+}
+
+// SendWithDelivery records the message like Send and returns a delivery
+// confirmed with the next message ID.
+func (c *Client) SendWithDelivery(ctx context.Context, room, text string) (*wirechat.Delivery, error) {
+	if err := c.check("SendWithDelivery", room, text); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.sent = append(c.sent, Message{Room: room, Text: text})
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+	return wirechat.ConfirmedDelivery(room, text, id), nil
+}
+
+// JoinID is Join for a room of the fake REST API.
+func (c *Client) JoinID(ctx context.Context, id int64) error {
+	room, err := c.roomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Join(ctx, room)
+}
+
+// LeaveID is Leave for a room of the fake REST API.
+func (c *Client) LeaveID(ctx context.Context, id int64) error {
+	room, err := c.roomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Leave(ctx, room)
+}
+
+// SendID is Send for a room of the fake REST API.
+func (c *Client) SendID(ctx context.Context, id int64, text string) error {
+	room, err := c.roomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, room, text)
+}
+
+// SendWithDeliveryID is SendWithDelivery for a room of the fake REST API.
+func (c *Client) SendWithDeliveryID(ctx context.Context, id int64, text string) (*wirechat.Delivery, error) {
+	room, err := c.roomName(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendWithDelivery(ctx, room, text)
+}
+
+// roomName resolves a room ID through the fake REST API, like Client.RoomName.
+func (c *Client) roomName(ctx context.Context, id int64) (string, error) {
+	api := c.RESTAPI()
+	if api == nil {
+		return "", wirechat.NewError(wirechat.ErrorInvalidConfig, "REST client not configured")
+	}
+	rooms, err := api.ListRooms(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, room := range rooms {
+		if room.ID == id {
+			return room.Name, nil
+		}
+	}
+	return "", wirechat.NewError(wirechat.ErrorRoomNotFound, "room not found")
+}
+
+// SendFrame records the command. Like Client, it rejects an empty type.
+func (c *Client) SendFrame(ctx context.Context, frameType string, payload any) error {
+	if err := c.check("SendFrame", frameType, payload); err != nil {
+		return err
+	}
+	if frameType == "" {
+		return wirechat.NewError(wirechat.ErrorBadRequest, "empty command type")
+	}
+	c.mu.Lock()
+	c.commands = append(c.commands, Command{Type: frameType, Payload: payload})
+	c.mu.Unlock()
+	return nil
+}
+
+// UpdateConfig applies fn to the fake configuration without validating it.
+func (c *Client) UpdateConfig(fn func(*wirechat.Config)) error {
+	if err := c.record("UpdateConfig"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := c.cfg
+	next.Endpoints = slices.Clone(next.Endpoints)
+	next.LaneWeights = maps.Clone(next.LaneWeights)
+	fn(&next)
+	c.cfg = next
+	return nil
+}
+
+// Config returns the configuration changed by UpdateConfig.
+func (c *Client) Config() wirechat.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg
+}
+
+// Latency returns the latency of the last successful EmitHeartbeat.
+func (c *Client) Latency() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latency
+}
+
+// check records a call that needs a connection and returns its error.
+func (c *Client) check(method string, args ...any) error {
+	if err := c.record(method, args...); err != nil {
+		return err
+	}
+	if c.State() != wirechat.StateConnected {
+		return wirechat.NewError(wirechat.ErrorNotConnected, "client not connected")
+	}
+	return nil
+}
+
+// RESTAPI returns the fake REST API, or nil if REST is nil.
+func (c *Client) RESTAPI() rest.API {
+	if c.REST == nil {
+		return nil
+	}
+	return c.REST
+}
+
+// OnMessage registers callback for message events.
+func (c *Client) OnMessage(fn func(wirechat.MessageEvent)) {
+	c.mu.Lock()
+	c.onMessage = fn
+	c.mu.Unlock()
+}
+
+// OnUserJoined registers callback for user joined events.
+func (c *Client) OnUserJoined(fn func(wirechat.UserEvent)) {
+	c.mu.Lock()
+	c.onUserJoined = fn
+	c.mu.Unlock()
+}
+
+// OnUserLeft registers callback for user left events.
+func (c *Client) OnUserLeft(fn func(wirechat.UserEvent)) {
+	c.mu.Lock()
+	c.onUserLeft = fn
+	c.mu.Unlock()
+}
+
+// OnHistory registers callback for history events.
+func (c *Client) OnHistory(fn func(wirechat.HistoryEvent)) {
+	c.mu.Lock()
+	c.onHistory = fn
+	c.mu.Unlock()
+}
+
+// OnError registers callback for errors.
+func (c *Client) OnError(fn func(error)) {
+	c.mu.Lock()
+	c.onError = fn
+	c.mu.Unlock()
+}
+
+// OnStateChanged registers callback for connection state changes.
+func (c *Client) OnStateChanged(fn func(wirechat.StateEvent)) {
+	c.mu.Lock()
+	c.onStateChanged = fn
+	c.mu.Unlock()
+}
+
+// OnHeartbeat registers callback for heartbeat results.
+func (c *Client) OnHeartbeat(fn func(wirechat.HeartbeatEvent)) {
+	c.mu.Lock()
+	c.onHeartbeat = fn
+	c.mu.Unlock()
+}
+
+// OnBreakerStateChanged registers callback for circuit breaker transitions.
+func (c *Client) OnBreakerStateChanged(fn func(wirechat.BreakerEvent)) {
+	c.mu.Lock()
+	c.onBreaker = fn
+	c.mu.Unlock()
+}
+
+// OnRawFrame registers callback for raw frames.
+func (c *Client) OnRawFrame(fn func(wirechat.Direction, []byte)) {
+	c.mu.Lock()
+	c.onRawFrame = fn
+	c.mu.Unlock()
+}
+
+// OnUnknownEvent registers callback for events without a handler.
+func (c *Client) OnUnknownEvent(fn func(wirechat.Outbound)) {
+	c.mu.Lock()
+	c.onUnknownEvent = fn
+	c.mu.Unlock()
+}
+
+// HandleEvent registers an untyped handler for a custom event, as used by
+// wirechat.RegisterEvent.
+func (c *Client) HandleEvent(event string, fn func(wirechat.Outbound) error) {
+	c.mu.Lock()
+	c.custom[event] = fn
+	c.mu.Unlock()
+}
+
+// EmitMessage delivers ev to the OnMessage handler.
+func (c *Client) EmitMessage(ev wirechat.MessageEvent) {
+	c.mu.Lock()
+	fn := c.onMessage
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitUserJoined delivers ev to the OnUserJoined handler.
+func (c *Client) EmitUserJoined(ev wirechat.UserEvent) {
+	c.mu.Lock()
+	fn := c.onUserJoined
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitUserLeft delivers ev to the OnUserLeft handler.
+func (c *Client) EmitUserLeft(ev wirechat.UserEvent) {
+	c.mu.Lock()
+	fn := c.onUserLeft
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitHistory delivers ev to the OnHistory handler.
+func (c *Client) EmitHistory(ev wirechat.HistoryEvent) {
+	c.mu.Lock()
+	fn := c.onHistory
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitError delivers err to the OnError handler.
+func (c *Client) EmitError(err error) {
+	c.mu.Lock()
+	fn := c.onError
+	c.mu.Unlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// EmitHeartbeat delivers ev to the OnHeartbeat handler. A successful
+// heartbeat also sets Latency.
+func (c *Client) EmitHeartbeat(ev wirechat.HeartbeatEvent) {
+	c.mu.Lock()
+	if ev.Error == nil {
+		c.latency = ev.Latency
+	}
+	fn := c.onHeartbeat
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitBreakerState delivers ev to the OnBreakerStateChanged handler.
+func (c *Client) EmitBreakerState(ev wirechat.BreakerEvent) {
+	c.mu.Lock()
+	fn := c.onBreaker
+	c.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// EmitRawFrame delivers a raw frame to the OnRawFrame handler.
+func (c *Client) EmitRawFrame(dir wirechat.Direction, data []byte) {
+	c.mu.Lock()
+	fn := c.onRawFrame
+	c.mu.Unlock()
+	if fn != nil {
+		fn(dir, data)
+	}
+}
+
+// EmitEvent delivers out to the handler registered for out.Event with
+// HandleEvent or wirechat.RegisterEvent, or to OnUnknownEvent if there is
+// none. A handler error is reported to OnError as ErrorSerialization.
+func (c *Client) EmitEvent(out wirechat.Outbound) {
+	c.mu.Lock()
+	fn, unknown := c.custom[out.Event], c.onUnknownEvent
+	c.mu.Unlock()
+	switch {
+	case fn != nil:
+		if err := fn(out); err != nil {
+			c.EmitError(wirechat.WrapError(wirechat.ErrorSerialization, "failed to unmarshal "+out.Event+" event", err))
+		}
+	case unknown != nil:
+		unknown(out)
+	}
+}
+
+// SetState changes the connection state and fires OnStateChanged if it differs.
+func (c *Client) SetState(state wirechat.ConnectionState, err error) {
+	c.mu.Lock()
+	old := c.state
+	c.state = state
+	fn := c.onStateChanged
+	c.mu.Unlock()
+	if fn != nil && old != state {
+		fn(wirechat.StateEvent{OldState: old, NewState: state, Transport: "fake", Error: err})
+	}
+}
+
+// Calls returns every recorded call in order.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.calls)
+}
+
+// CallCount returns how many times method was called.
+func (c *Client) CallCount(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, call := range c.calls {
+		if call.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Sent returns the messages passed to Send, in order.
+func (c *Client) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.sent)
+}
+
+// Commands returns the frames passed to SendFrame, in order.
+func (c *Client) Commands() []Command {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.commands)
+}
+
+// Rooms returns the joined rooms in sorted order.
+func (c *Client) Rooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// Reset clears recorded calls, sent messages and commands, keeping state,
+// rooms and handlers.
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = nil
+	c.sent = nil
+	c.commands = nil
+}
@@ -0,0 +1,147 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// echo is the kind of consumer code the fake is meant for.
+func echo(c wirechat.ChatClient) {
+	c.OnMessage(func(ev wirechat.MessageEvent) {
+		_ = c.Send(context.Background(), ev.Room, "echo: "+ev.Text)
+	})
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	fc := New()
+	echo(fc)
+
+	var states []wirechat.ConnectionState
+	fc.OnStateChanged(func(ev wirechat.StateEvent) { states = append(states, ev.NewState) })
+
+	if err := fc.Join(ctx, "general"); !errors.Is(err, wirechat.NewError(wirechat.ErrorNotConnected, "")) {
+		t.Fatalf("expected not_connected before Connect, got %v", err)
+	}
+	if err := fc.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := fc.Join(ctx, "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	fc.EmitMessage(wirechat.MessageEvent{Room: "general", User: "bob", Text: "hi"})
+	if sent := fc.Sent(); len(sent) != 1 || sent[0] != (Message{Room: "general", Text: "echo: hi"}) {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	if got := fc.Rooms(); len(got) != 1 || got[0] != "general" {
+		t.Fatalf("unexpected rooms: %v", got)
+	}
+	if fc.CallCount("Send") != 1 || fc.Calls()[0].Method != "Join" {
+		t.Fatalf("unexpected calls: %+v", fc.Calls())
+	}
+
+	boom := errors.New("boom")
+	fc.Fail("Send", boom)
+	if err := fc.Send(ctx, "general", "x"); !errors.Is(err, boom) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+
+	fc.SetState(wirechat.StateReconnecting, nil)
+	if len(states) != 2 || states[0] != wirechat.StateConnected || states[1] != wirechat.StateReconnecting {
+		t.Fatalf("unexpected states: %v", states)
+	}
+}
+
+func TestClientExtensions(t *testing.T) {
+	type moderation struct {
+		Target string `json:"target"`
+	}
+
+	ctx := context.Background()
+	fc := New()
+	general := fc.REST.AddRoom(rest.RoomInfo{Name: "general"})
+	if err := fc.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	// Rooms given by ID resolve through the fake REST API
+	if err := fc.JoinID(ctx, general.ID); err != nil {
+		t.Fatalf("join by id: %v", err)
+	}
+	if err := fc.SendID(ctx, 999, "x"); !errors.Is(err, wirechat.NewError(wirechat.ErrorRoomNotFound, "")) {
+		t.Fatalf("expected room_not_found, got %v", err)
+	}
+	d, err := fc.SendWithDeliveryID(ctx, general.ID, "hi")
+	if err != nil || d.Status() != wirechat.DeliveryConfirmed || d.ID() != 1 || d.Room != "general" {
+		t.Fatalf("unexpected delivery %+v (%v)", d, err)
+	}
+
+	// The generic helpers work against the interface
+	var c wirechat.ChatClient = fc
+	var got moderation
+	var errGot error
+	var unknown string
+	wirechat.RegisterEvent(c, "moderation", func(ev moderation) { got = ev })
+	c.OnError(func(err error) { errGot = err })
+	c.OnUnknownEvent(func(out wirechat.Outbound) { unknown = out.Event })
+	fc.EmitEvent(wirechat.Outbound{Type: "event", Event: "moderation", Data: []byte(`{"target":"bob"}`)})
+	fc.EmitEvent(wirechat.Outbound{Type: "event", Event: "moderation", Data: []byte(`{"target":1}`)})
+	fc.EmitEvent(wirechat.Outbound{Type: "event", Event: "poll"})
+	if got.Target != "bob" || unknown != "poll" || !errors.Is(errGot, wirechat.NewError(wirechat.ErrorSerialization, "")) {
+		t.Fatalf("unexpected events: %+v %q %v", got, unknown, errGot)
+	}
+	if err := wirechat.SendCommand(c, ctx, "typing", map[string]string{"room": "general"}); err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cmds := fc.Commands(); len(cmds) != 1 || cmds[0].Type != "typing" {
+		t.Fatalf("unexpected commands: %+v", cmds)
+	}
+
+	var beat wirechat.HeartbeatEvent
+	c.OnHeartbeat(func(ev wirechat.HeartbeatEvent) { beat = ev })
+	fc.EmitHeartbeat(wirechat.HeartbeatEvent{Latency: 42 * time.Millisecond})
+	if beat.Latency != 42*time.Millisecond || c.Latency() != 42*time.Millisecond {
+		t.Fatalf("unexpected heartbeat %+v, latency %s", beat, c.Latency())
+	}
+
+	if err := c.UpdateConfig(func(cfg *wirechat.Config) { cfg.Token = "new" }); err != nil || fc.Config().Token != "new" {
+		t.Fatalf("config not updated: %v", err)
+	}
+}
+
+func TestREST(t *testing.T) {
+	ctx := context.Background()
+	fr := NewREST()
+	room, err := fr.CreateRoom(ctx, rest.CreateRoomRequest{Name: "general"})
+	if err != nil || room.ID != 1 || room.Type != rest.RoomTypePublic {
+		t.Fatalf("create room: %+v, %v", room, err)
+	}
+	for id := int64(1); id <= 5; id++ {
+		fr.AddMessages(room.ID, rest.MessageInfo{ID: id, Body: "m"})
+	}
+
+	page, err := fr.GetMessages(ctx, room.ID, 2, nil)
+	if err != nil || len(page.Messages) != 2 || page.Messages[0].ID != 5 || !page.HasMore {
+		t.Fatalf("first page: %+v, %v", page, err)
+	}
+	before := page.Messages[1].ID
+	page, _ = fr.GetMessages(ctx, room.ID, 10, &before)
+	if len(page.Messages) != 3 || page.Messages[0].ID != 3 || page.HasMore {
+		t.Fatalf("second page: %+v", page)
+	}
+
+	dm1, _ := fr.CreateDirectRoom(ctx, rest.CreateDirectRoomRequest{UserID: 7})
+	dm2, _ := fr.CreateDirectRoom(ctx, rest.CreateDirectRoomRequest{UserID: 7})
+	if dm1.ID != dm2.ID {
+		t.Fatal("direct room should be idempotent")
+	}
+	if rooms, _ := fr.ListRooms(ctx); len(rooms) != 2 {
+		t.Fatalf("unexpected rooms: %+v", rooms)
+	}
+}
@@ -0,0 +1,207 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// Token is the token returned by the fake authentication endpoints.
+const Token = "fake-token"
+
+// REST is an in-memory fake of rest.API. Rooms and messages can be seeded
+// with AddRoom and AddMessages; calls are recorded like on Client.
+type REST struct {
+	mu       sync.Mutex
+	token    string
+	rooms    []rest.RoomInfo
+	messages map[int64][]rest.MessageInfo // Ordered by ID
+	nextID   int64
+	calls    []Call
+	failures map[string]error
+}
+
+var _ rest.API = (*REST)(nil)
+
+// NewREST returns an empty fake REST API.
+func NewREST() *REST {
+	return &REST{
+		messages: make(map[int64][]rest.MessageInfo),
+		nextID:   1,
+		failures: make(map[string]error),
+	}
+}
+
+// Fail makes every later call of method (e.g. "ListRooms") return err until
+// Fail is called again with a nil error.
+func (r *REST) Fail(method string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		delete(r.failures, method)
+		return
+	}
+	r.failures[method] = err
+}
+
+// recordLocked stores the call and returns the injected failure for it, if any.
+func (r *REST) recordLocked(method string, args ...any) error {
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	return r.failures[method]
+}
+
+// Calls returns every recorded call in order.
+func (r *REST) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+// Token returns the token set through SetToken.
+func (r *REST) Token() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// AddRoom seeds a room. A zero ID is replaced by the next free one.
+func (r *REST) AddRoom(room rest.RoomInfo) rest.RoomInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addRoomLocked(room)
+}
+
+func (r *REST) addRoomLocked(room rest.RoomInfo) rest.RoomInfo {
+	if room.ID == 0 {
+		room.ID = r.nextID
+	}
+	r.nextID = max(r.nextID, room.ID+1)
+	if room.CreatedAt.IsZero() {
+		room.CreatedAt = time.Now()
+	}
+	r.rooms = append(r.rooms, room)
+	return room
+}
+
+// AddMessages seeds the history of a room. Messages are kept ordered by ID.
+func (r *REST) AddMessages(roomID int64, msgs ...rest.MessageInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		m.RoomID = roomID
+		r.messages[roomID] = append(r.messages[roomID], m)
+	}
+	slices.SortFunc(r.messages[roomID], func(a, b rest.MessageInfo) int { return int(a.ID - b.ID) })
+}
+
+// SetToken records the token.
+func (r *REST) SetToken(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: "SetToken", Args: []any{token}})
+	r.token = token
+}
+
+// Register returns Token.
+func (r *REST) Register(ctx context.Context, req rest.RegisterRequest) (*rest.TokenResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("Register", req); err != nil {
+		return nil, err
+	}
+	return &rest.TokenResponse{Token: Token}, nil
+}
+
+// Login returns Token.
+func (r *REST) Login(ctx context.Context, req rest.LoginRequest) (*rest.TokenResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("Login", req); err != nil {
+		return nil, err
+	}
+	return &rest.TokenResponse{Token: Token}, nil
+}
+
+// GuestLogin returns Token.
+func (r *REST) GuestLogin(ctx context.Context) (*rest.TokenResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("GuestLogin"); err != nil {
+		return nil, err
+	}
+	return &rest.TokenResponse{Token: Token}, nil
+}
+
+// CreateRoom adds a room with the next free ID.
+func (r *REST) CreateRoom(ctx context.Context, req rest.CreateRoomRequest) (*rest.RoomInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("CreateRoom", req); err != nil {
+		return nil, err
+	}
+	if req.Type == "" {
+		req.Type = rest.RoomTypePublic
+	}
+	room := r.addRoomLocked(rest.RoomInfo{Name: req.Name, Type: req.Type})
+	return &room, nil
+}
+
+// ListRooms returns the seeded and created rooms.
+func (r *REST) ListRooms(ctx context.Context) ([]rest.RoomInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("ListRooms"); err != nil {
+		return nil, err
+	}
+	return slices.Clone(r.rooms), nil
+}
+
+// CreateDirectRoom returns the direct room for the peer, creating it on first use.
+func (r *REST) CreateDirectRoom(ctx context.Context, req rest.CreateDirectRoomRequest) (*rest.RoomInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.recordLocked("CreateDirectRoom", req); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("dm-%d", req.UserID)
+	for _, room := range r.rooms {
+		if room.Type == rest.RoomTypeDirect && room.Name == name {
+			return &room, nil
+		}
+	}
+	room := r.addRoomLocked(rest.RoomInfo{Name: name, Type: rest.RoomTypeDirect})
+	return &room, nil
+}
+
+// GetMessages pages through the seeded history newest first, like the server.
+func (r *REST) GetMessages(ctx context.Context, roomID int64, limit int, before *int64) (*rest.MessagesResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b any
+	if before != nil {
+		b = *before
+	}
+	if err := r.recordLocked("GetMessages", roomID, limit, b); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var page []rest.MessageInfo
+	msgs := r.messages[roomID]
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if before != nil && msgs[i].ID >= *before {
+			continue
+		}
+		if len(page) == limit {
+			return &rest.MessagesResponse{Messages: page, HasMore: true}, nil
+		}
+		page = append(page, msgs[i])
+	}
+	return &rest.MessagesResponse{Messages: page}, nil
+}
@@ -0,0 +1,72 @@
+package wirechat
+
+import (
+	"context"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/internal"
+)
+
+// Latency returns the round-trip time measured by the last successful heartbeat.
+// It returns 0 if heartbeats are disabled or none has succeeded yet.
+func (c *Client) Latency() time.Duration {
+	return time.Duration(c.latency.Load())
+}
+
+// startHeartbeat launches the keepalive loop for a connection if enabled.
+func (c *Client) startHeartbeat(ctx context.Context, conn *internal.Conn) {
+	if c.config().HeartbeatInterval <= 0 {
+		return
+	}
+	go c.heartbeatLoop(ctx, conn)
+}
+
+// heartbeatLoop pings the server periodically. After HeartbeatMaxMisses
+// consecutive failures the connection is dropped so readLoop runs the
+// regular disconnect and reconnect path.
+func (c *Client) heartbeatLoop(ctx context.Context, conn *internal.Conn) {
+	ticker := time.NewTicker(c.config().HeartbeatInterval)
+	defer ticker.Stop()
+
+	timeout := c.config().HeartbeatTimeout
+	if timeout <= 0 {
+		timeout = c.config().HeartbeatInterval
+	}
+
+	missed := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		// Stop once the connection has been replaced by a reconnect
+		if c.currentConn() != conn {
+			return
+		}
+
+		rtt, err := conn.Ping(ctx, timeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			missed++
+			wireErr := WrapError(ErrorTimeout, "heartbeat failed", err)
+			c.dispatcher.fireHeartbeat(HeartbeatEvent{Missed: missed, Error: wireErr})
+			c.logger.Warn("heartbeat missed", map[string]any{"missed": missed, "error": err.Error()})
+
+			if missed >= c.config().HeartbeatMaxMisses {
+				c.logger.Warn("heartbeat threshold reached, dropping connection", map[string]any{"missed": missed})
+				_ = conn.CloseNow()
+				return
+			}
+			continue
+		}
+
+		missed = 0
+		c.latency.Store(int64(rtt))
+		c.endpoints.observe(c.Endpoint(), rtt)
+		c.dispatcher.fireHeartbeat(HeartbeatEvent{Latency: rtt})
+	}
+}
@@ -0,0 +1,59 @@
+package wirechat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func TestHeartbeatLatency(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.CloseNow()
+		// Reading keeps the server answering pings until the client goes away
+		for {
+			if _, _, err := ws.Read(r.Context()); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.URL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	cfg.HeartbeatInterval = 10 * time.Millisecond
+	c := NewClient(&cfg)
+
+	beats := make(chan HeartbeatEvent, 1)
+	c.OnHeartbeat(func(ev HeartbeatEvent) {
+		select {
+		case beats <- ev:
+		default:
+		}
+	})
+
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case ev := <-beats:
+		if ev.Error != nil || ev.Latency <= 0 {
+			t.Fatalf("unexpected heartbeat: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no heartbeat received")
+	}
+	if c.Latency() <= 0 {
+		t.Fatalf("expected latency to be recorded")
+	}
+}
@@ -0,0 +1,126 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDropFrame can be returned by an interceptor to silently discard a frame.
+var ErrDropFrame = errors.New("wirechat: drop frame")
+
+// OutgoingInterceptor inspects a frame before it is written to the socket.
+// It returns the frame to send (possibly modified), ErrDropFrame to discard it,
+// or any other error to reject it.
+type OutgoingInterceptor func(ctx context.Context, in Inbound) (Inbound, error)
+
+// IncomingInterceptor inspects a frame before it is dispatched to handlers.
+// It returns the frame to dispatch (possibly modified), ErrDropFrame to discard it,
+// or any other error to reject it.
+type IncomingInterceptor func(ctx context.Context, out Outbound) (Outbound, error)
+
+// ConnInfo describes the connection a frame travels over.
+type ConnInfo struct {
+	URL         string
+	User        string
+	Protocol    int
+	Transport   string
+	ConnectedAt time.Time
+}
+
+type connInfoKey struct{}
+
+// ConnInfoFromContext returns the connection metadata passed to interceptors.
+func ConnInfoFromContext(ctx context.Context) (ConnInfo, bool) {
+	info, ok := ctx.Value(connInfoKey{}).(ConnInfo)
+	return info, ok
+}
+
+// InterceptOutgoing appends interceptors run in order on every outgoing frame.
+// Register interceptors before calling Connect.
+func (c *Client) InterceptOutgoing(fns ...OutgoingInterceptor) {
+	c.outgoing = append(c.outgoing, fns...)
+}
+
+// InterceptIncoming appends interceptors run in order on every incoming frame.
+// Register interceptors before calling Connect.
+func (c *Client) InterceptIncoming(fns ...IncomingInterceptor) {
+	c.incoming = append(c.incoming, fns...)
+}
+
+// interceptorContext attaches connection metadata to ctx.
+func (c *Client) interceptorContext(ctx context.Context) context.Context {
+	protocol := c.config().Protocol
+	if protocol == 0 {
+		protocol = ProtocolVersion
+	}
+
+	c.mu.Lock()
+	connectedAt := c.connectedAt
+	transportName := c.transportName
+	endpoint := c.endpoint.URL
+	c.mu.Unlock()
+	if endpoint == "" {
+		// Not connected yet; report the endpoint the next dial would use
+		endpoint = c.endpoints.pick().URL
+	}
+
+	return context.WithValue(ctx, connInfoKey{}, ConnInfo{
+		URL:         endpoint,
+		User:        c.config().User,
+		Protocol:    protocol,
+		Transport:   transportName,
+		ConnectedAt: connectedAt,
+	})
+}
+
+// interceptOutgoing runs the outgoing chain. The returned error is
+// ErrDropFrame or a WirechatError with ErrorRejected.
+func (c *Client) interceptOutgoing(ctx context.Context, in Inbound) (Inbound, error) {
+	if len(c.outgoing) == 0 {
+		return in, nil
+	}
+	ctx = c.interceptorContext(ctx)
+	for _, fn := range c.outgoing {
+		var err error
+		if in, err = fn(ctx, in); err != nil {
+			return in, rejection(err, "outgoing frame rejected by interceptor")
+		}
+	}
+	return in, nil
+}
+
+// interceptIncoming runs the incoming chain. The returned error is
+// ErrDropFrame or a WirechatError with ErrorRejected.
+func (c *Client) interceptIncoming(ctx context.Context, out Outbound) (Outbound, error) {
+	if len(c.incoming) == 0 {
+		return out, nil
+	}
+	ctx = c.interceptorContext(ctx)
+	for _, fn := range c.incoming {
+		var err error
+		if out, err = fn(ctx, out); err != nil {
+			return out, rejection(err, "incoming frame rejected by interceptor")
+		}
+	}
+	return out, nil
+}
+
+func rejection(err error, msg string) error {
+	if errors.Is(err, ErrDropFrame) {
+		return ErrDropFrame
+	}
+	return WrapError(ErrorRejected, msg, err)
+}
+
+// writeHello sends the hello frame through the outgoing interceptors.
+func (c *Client) writeHello(ctx context.Context, hello Inbound) error {
+	hello, err := c.interceptOutgoing(ctx, hello)
+	if errors.Is(err, ErrDropFrame) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.conn.Write(ctx, hello)
+}
@@ -0,0 +1,43 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.URL = "ws://example/ws"
+	c := NewClient(&cfg)
+
+	c.InterceptOutgoing(func(ctx context.Context, in Inbound) (Inbound, error) {
+		if info, ok := ConnInfoFromContext(ctx); !ok || info.URL != cfg.URL {
+			t.Fatalf("missing connection metadata: %+v", info)
+		}
+		if p, ok := in.Data.(MsgPayload); ok {
+			p.Text = strings.ReplaceAll(p.Text, "secret", "******")
+			in.Data = p
+		}
+		return in, nil
+	})
+	c.InterceptIncoming(func(_ context.Context, out Outbound) (Outbound, error) {
+		if out.Event == eventUserJoined {
+			return out, ErrDropFrame
+		}
+		return out, errors.New("spam")
+	})
+
+	in, err := c.interceptOutgoing(context.Background(), Inbound{Type: inboundMsg, Data: MsgPayload{Room: "r", Text: "my secret"}})
+	if err != nil || in.Data.(MsgPayload).Text != "my ******" {
+		t.Fatalf("unexpected outgoing result: %+v %v", in, err)
+	}
+
+	if _, err := c.interceptIncoming(context.Background(), Outbound{Type: outboundEvent, Event: eventUserJoined}); !errors.Is(err, ErrDropFrame) {
+		t.Fatalf("expected drop, got %v", err)
+	}
+	if _, err := c.interceptIncoming(context.Background(), Outbound{Type: outboundEvent, Event: eventMessage}); !errors.Is(err, NewError(ErrorRejected, "")) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+}
@@ -2,21 +2,41 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/coder/websocket"
-	"github.com/coder/websocket/wsjson"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
 )
 
-// Conn wraps websocket.Conn with timeouts.
+// ErrDecode reports a frame that was received intact but could not be decoded.
+// The connection remains usable after such an error.
+var ErrDecode = errors.New("decode frame")
+
+// RawHook observes every frame on the wire. outgoing is false for frames read from the server.
+type RawHook func(outgoing bool, data []byte)
+
+// Conn wraps a transport.FrameConn with timeouts and a frame codec.
 type Conn struct {
-	ws           *websocket.Conn
+	fc           transport.FrameConn
+	codec        codec.Codec
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	rawHook      RawHook
+}
+
+// NewConn wraps fc. A nil codec defaults to codec.JSON.
+func NewConn(fc transport.FrameConn, cd codec.Codec, readTimeout, writeTimeout time.Duration) *Conn {
+	if cd == nil {
+		cd = codec.JSON
+	}
+	return &Conn{fc: fc, codec: cd, readTimeout: readTimeout, writeTimeout: writeTimeout}
 }
 
-func NewConn(ws *websocket.Conn, readTimeout, writeTimeout time.Duration) *Conn {
-	return &Conn{ws: ws, readTimeout: readTimeout, writeTimeout: writeTimeout}
+// SetRawHook sets the hook called with the raw bytes of every frame.
+func (c *Conn) SetRawHook(fn RawHook) {
+	c.rawHook = fn
 }
 
 func (c *Conn) Read(ctx context.Context, v any) error {
@@ -25,18 +45,66 @@ func (c *Conn) Read(ctx context.Context, v any) error {
 		ctx, cancel = context.WithTimeout(ctx, c.readTimeout)
 		defer cancel()
 	}
-	return wsjson.Read(ctx, c.ws, v)
+	typ, data, err := c.fc.ReadFrame(ctx)
+	if err != nil {
+		return err
+	}
+	if c.rawHook != nil {
+		c.rawHook(false, data)
+	}
+	if want := c.messageType(); typ != want {
+		return fmt.Errorf("%w: expected %v message, got %v", ErrDecode, want, typ)
+	}
+	if err := c.codec.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return nil
 }
 
 func (c *Conn) Write(ctx context.Context, v any) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal frame: %w", err)
+	}
 	if c.writeTimeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, c.writeTimeout)
 		defer cancel()
 	}
-	return wsjson.Write(ctx, c.ws, v)
+	if c.rawHook != nil {
+		c.rawHook(true, data)
+	}
+	return c.fc.WriteFrame(ctx, c.messageType(), data)
+}
+
+func (c *Conn) messageType() transport.MessageType {
+	if c.codec.Binary() {
+		return transport.MessageBinary
+	}
+	return transport.MessageText
+}
+
+// Close closes the connection gracefully.
+func (c *Conn) Close() error {
+	return c.fc.Close()
+}
+
+// Ping sends a WebSocket ping and waits for the matching pong.
+// It returns the measured round-trip time.
+func (c *Conn) Ping(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	if err := c.fc.Ping(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
 }
 
-func (c *Conn) Close(code websocket.StatusCode, reason string) error {
-	return c.ws.Close(code, reason)
+// CloseNow closes the connection without a close handshake.
+func (c *Conn) CloseNow() error {
+	return c.fc.CloseNow()
 }
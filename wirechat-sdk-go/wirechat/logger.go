@@ -1,5 +1,11 @@
 package wirechat
 
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
 // Logger is a minimal logging interface accepted by the SDK.
 type Logger interface {
 	Debug(msg string, fields map[string]any)
@@ -15,3 +21,121 @@ func (noopLogger) Debug(string, map[string]any) {}
 func (noopLogger) Info(string, map[string]any)  {}
 func (noopLogger) Warn(string, map[string]any)  {}
 func (noopLogger) Error(string, map[string]any) {}
+
+// slogLogger is a Logger writing to a slog.Handler.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger backed by h.
+// If h is nil, the handler of slog.Default() is used.
+func NewSlogLogger(h slog.Handler) Logger {
+	if h == nil {
+		h = slog.Default().Handler()
+	}
+	return slogLogger{logger: slog.New(h)}
+}
+
+func (l slogLogger) Debug(msg string, fields map[string]any) { l.log(slog.LevelDebug, msg, fields) }
+func (l slogLogger) Info(msg string, fields map[string]any)  { l.log(slog.LevelInfo, msg, fields) }
+func (l slogLogger) Warn(msg string, fields map[string]any)  { l.log(slog.LevelWarn, msg, fields) }
+func (l slogLogger) Error(msg string, fields map[string]any) { l.log(slog.LevelError, msg, fields) }
+
+func (l slogLogger) log(level slog.Level, msg string, fields map[string]any) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	// Sort keys so the output is stable across runs
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// NewSlog returns a *slog.Logger that forwards records to l.
+// Levels map to the nearest Logger method; groups are flattened into dotted keys.
+func NewSlog(l Logger) *slog.Logger {
+	if l == nil {
+		l = noopLogger{}
+	}
+	return slog.New(&loggerHandler{logger: l})
+}
+
+// loggerHandler is a slog.Handler writing to a Logger.
+type loggerHandler struct {
+	logger Logger
+	attrs  []slog.Attr // Attributes from WithAttrs, keys already prefixed
+	prefix string      // Dotted group prefix from WithGroup
+}
+
+func (h *loggerHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(fields, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+
+	switch {
+	case r.Level < slog.LevelInfo:
+		h.logger.Debug(r.Message, fields)
+	case r.Level < slog.LevelWarn:
+		h.logger.Info(r.Message, fields)
+	case r.Level < slog.LevelError:
+		h.logger.Warn(r.Message, fields)
+	default:
+		h.logger.Error(r.Message, fields)
+	}
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		next.attrs = append(next.attrs, a)
+	}
+	return &next
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// addAttr stores a resolved attribute, flattening groups into dotted keys.
+func addAttr(fields map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(fields, groupPrefix, ga)
+		}
+		return
+	}
+	fields[prefix+a.Key] = a.Value.Any()
+}
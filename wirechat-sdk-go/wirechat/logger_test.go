@@ -0,0 +1,30 @@
+package wirechat
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type recordLogger struct {
+	noopLogger
+	msg    string
+	fields map[string]any
+}
+
+func (l *recordLogger) Warn(msg string, fields map[string]any) { l.msg, l.fields = msg, fields }
+
+func TestSlogBridge(t *testing.T) {
+	rec := &recordLogger{}
+	NewSlog(rec).With("room", "general").WithGroup("conn").Warn("lost", "attempt", 2)
+	if rec.msg != "lost" || rec.fields["room"] != "general" || rec.fields["conn.attempt"] != int64(2) {
+		t.Fatalf("unexpected record: %q %v", rec.msg, rec.fields)
+	}
+
+	var buf bytes.Buffer
+	NewSlogLogger(slog.NewTextHandler(&buf, nil)).Warn("reconnecting", map[string]any{"delay": "1s", "attempt": 1})
+	if !strings.Contains(buf.String(), "msg=reconnecting attempt=1 delay=1s") {
+		t.Fatalf("unexpected slog output: %s", buf.String())
+	}
+}
@@ -0,0 +1,51 @@
+package wirechat
+
+import (
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// Message is the unified chat message model. MessageEvent (WebSocket) and
+// rest.MessageInfo (REST) describe the same message in different shapes;
+// Message carries the fields of both and converts either way.
+type Message struct {
+	ID     int64     `json:"id"`                // Server-assigned ID
+	Room   string    `json:"room"`              // Room name
+	RoomID int64     `json:"room_id,omitempty"` // Room ID (0 if only the name is known)
+	User   string    `json:"user"`              // Author name
+	UserID int64     `json:"user_id,omitempty"` // Author ID (0 for WebSocket events, which carry none)
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// MessageFromEvent converts a WebSocket message event. The event carries no
+// room or user ID, so RoomID and UserID are left zero. An event without a
+// timestamp (TS 0) leaves Time zero rather than setting it to 1970.
+func MessageFromEvent(ev MessageEvent) Message {
+	m := Message{ID: ev.ID, Room: ev.Room, User: ev.User, Text: ev.Text}
+	if ev.TS != 0 {
+		m.Time = time.Unix(ev.TS, 0)
+	}
+	return m
+}
+
+// MessageFromInfo converts a REST history entry. REST does not report the
+// room name, so it is passed in.
+func MessageFromInfo(room string, m rest.MessageInfo) Message {
+	return Message{ID: m.ID, Room: room, RoomID: m.RoomID, User: m.User, UserID: m.UserID, Text: m.Body, Time: m.CreatedAt}
+}
+
+// Event converts m to the WebSocket shape. TS has second precision.
+func (m Message) Event() MessageEvent {
+	var ts int64
+	if !m.Time.IsZero() {
+		ts = m.Time.Unix()
+	}
+	return MessageEvent{ID: m.ID, Room: m.Room, User: m.User, Text: m.Text, TS: ts}
+}
+
+// Info converts m to the REST shape.
+func (m Message) Info() rest.MessageInfo {
+	return rest.MessageInfo{ID: m.ID, RoomID: m.RoomID, UserID: m.UserID, User: m.User, Body: m.Text, CreatedAt: m.Time}
+}
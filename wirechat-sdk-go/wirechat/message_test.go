@@ -0,0 +1,28 @@
+package wirechat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+func TestMessageFromEvent(t *testing.T) {
+	if m := MessageFromEvent(MessageEvent{ID: 1, Room: "general"}); !m.Time.IsZero() {
+		t.Fatalf("missing TS converted to %v", m.Time)
+	}
+	if m := MessageFromEvent(MessageEvent{ID: 1, TS: 1700000000}); m.Time.Unix() != 1700000000 || m.Event().TS != 1700000000 {
+		t.Fatalf("unexpected time %v", m.Time)
+	}
+
+	// A REST copy fills in the time of an event without a timestamp, so the
+	// message is found by Range
+	created := time.Unix(1700000000, 0).UTC()
+	store := NewMemoryStore()
+	_ = store.Append("general", MessageFromEvent(MessageEvent{ID: 1, Room: "general", Text: "hi"}))
+	_ = store.Append("general", MessageFromInfo("general", rest.MessageInfo{ID: 1, RoomID: 7, Body: "hi", CreatedAt: created}))
+	got, _ := store.Range("general", created, created.Add(time.Second))
+	if len(got) != 1 || !got[0].Time.Equal(created) {
+		t.Fatalf("unexpected range: %+v", got)
+	}
+}
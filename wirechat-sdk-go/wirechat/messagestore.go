@@ -0,0 +1,195 @@
+package wirechat
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// MessageStore caches messages per room name so apps can render history
+// offline and skip redundant REST calls. Messages are unique by ID; appending
+// a known ID only fills in fields the stored copy lacks (e.g. RoomID from
+// REST), and messages without an ID (guests) are not stored. Query results
+// are ordered by ascending ID.
+type MessageStore interface {
+	Append(room string, msgs ...Message) error
+	Latest(room string, n int) ([]Message, error)
+	Before(room string, id int64, n int) ([]Message, error)
+	After(room string, id int64, n int) ([]Message, error)
+	Range(room string, from, to time.Time) ([]Message, error) // from inclusive, to exclusive
+	Close() error
+}
+
+// MemoryStore is a MessageStore that keeps messages in memory.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	rooms map[string][]Message // Sorted by ID
+}
+
+var _ MessageStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty in-memory message store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rooms: make(map[string][]Message)}
+}
+
+// Append adds messages that are not stored yet.
+func (s *MemoryStore) Append(room string, msgs ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.rooms[room]
+	for _, m := range msgs {
+		if m.ID == 0 {
+			continue
+		}
+		i, found := slices.BinarySearchFunc(stored, m.ID, byMessageID)
+		if found {
+			stored[i], _ = mergeMessage(stored[i], m)
+		} else {
+			stored = slices.Insert(stored, i, m)
+		}
+	}
+	s.rooms[room] = stored
+	return nil
+}
+
+// Latest returns the n newest messages.
+func (s *MemoryStore) Latest(room string, n int) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(latestN(s.rooms[room], n)), nil
+}
+
+// Before returns up to n messages older than id, newest last.
+func (s *MemoryStore) Before(room string, id int64, n int) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(beforeID(s.rooms[room], id, n, messageID)), nil
+}
+
+// After returns up to n messages newer than id, oldest first.
+func (s *MemoryStore) After(room string, id int64, n int) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(afterID(s.rooms[room], id, n, messageID)), nil
+}
+
+// Range returns the messages sent in [from, to).
+func (s *MemoryStore) Range(room string, from, to time.Time) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Message
+	for _, m := range s.rooms[room] {
+		if inRange(m.Time, from, to) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// Close releases nothing; it exists to satisfy MessageStore.
+func (s *MemoryStore) Close() error { return nil }
+
+func messageID(m Message) int64 { return m.ID }
+
+func byMessageID(m Message, id int64) int { return cmp.Compare(m.ID, id) }
+
+// latestN returns the last n items of a slice sorted by ID.
+func latestN[T any](items []T, n int) []T {
+	if n <= 0 {
+		return nil
+	}
+	return items[max(0, len(items)-n):]
+}
+
+// beforeID returns the last n items with an ID below id.
+func beforeID[T any](items []T, id int64, n int, idOf func(T) int64) []T {
+	end, _ := slices.BinarySearchFunc(items, id, func(item T, id int64) int { return cmp.Compare(idOf(item), id) })
+	return latestN(items[:end], n)
+}
+
+// afterID returns the first n items with an ID above id.
+func afterID[T any](items []T, id int64, n int, idOf func(T) int64) []T {
+	start, found := slices.BinarySearchFunc(items, id, func(item T, id int64) int { return cmp.Compare(idOf(item), id) })
+	if found {
+		start++
+	}
+	if n <= 0 {
+		return nil
+	}
+	return items[start:min(len(items), start+n)]
+}
+
+// mergeMessage fills the zero fields of stored from m and reports whether
+// anything changed.
+func mergeMessage(stored, m Message) (Message, bool) {
+	merged := stored
+	if merged.Room == "" {
+		merged.Room = m.Room
+	}
+	if merged.RoomID == 0 {
+		merged.RoomID = m.RoomID
+	}
+	if merged.User == "" {
+		merged.User = m.User
+	}
+	if merged.UserID == 0 {
+		merged.UserID = m.UserID
+	}
+	if merged.Text == "" {
+		merged.Text = m.Text
+	}
+	if merged.Time.IsZero() {
+		merged.Time = m.Time
+	}
+	return merged, merged != stored
+}
+
+func inRange(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// storeFeed feeds the configured MessageStore from WebSocket events and REST
+// responses. REST pages are stored once the room resolver knows the room
+// name from a ListRooms, CreateRoom or CreateDirectRoom response.
+type storeFeed struct {
+	c     *Client
+	store MessageStore
+}
+
+func (f *storeFeed) hooks() eventHooks {
+	return eventHooks{
+		message: func(ev MessageEvent) { f.append(ev.Room, MessageFromEvent(ev)) },
+		history: func(ev HistoryEvent) {
+			msgs := make([]Message, len(ev.Messages))
+			for i, m := range ev.Messages {
+				msgs[i] = MessageFromEvent(m)
+			}
+			f.append(ev.Room, msgs...)
+		},
+	}
+}
+
+// observeMessages stores a REST page if the room name is known.
+func (f *storeFeed) observeMessages(roomID int64, msgs []rest.MessageInfo) {
+	room, ok := f.c.rooms.cachedName(roomID)
+	if !ok {
+		f.c.logger.Debug("message page not cached, unknown room name", map[string]any{"room_id": roomID})
+		return
+	}
+	converted := make([]Message, len(msgs))
+	for i, m := range msgs {
+		converted[i] = MessageFromInfo(room, m)
+	}
+	f.append(room, converted...)
+}
+
+func (f *storeFeed) append(room string, msgs ...Message) {
+	if err := f.store.Append(room, msgs...); err != nil {
+		f.c.logger.Error("message store append failed", map[string]any{"room": room, "error": err.Error()})
+		f.c.dispatcher.fireError(WrapError(ErrorUnknown, "message store append failed", err))
+	}
+}
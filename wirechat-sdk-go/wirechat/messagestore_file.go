@@ -0,0 +1,489 @@
+package wirechat
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSegmentSize is the number of records per log segment.
+const defaultSegmentSize = 1000
+
+// roomDirPrefix starts the directory name of a room whose name follows hex
+// encoded, so any name, including "", "." and "..", maps to a single path
+// element inside the store directory.
+const roomDirPrefix = "r"
+
+// hashedDirPrefix starts the directory name of a room whose name is longer
+// than maxEncodedRoom bytes. The SHA-256 of the name follows, and the name
+// itself is kept in the roomNameFile inside the directory, since hex encoding
+// it would exceed the file name limit of common file systems (255 bytes).
+const (
+	hashedDirPrefix = "h"
+	maxEncodedRoom  = 100
+	roomNameFile    = "room"
+)
+
+// FileStore is an embedded MessageStore that keeps one directory per room.
+// Each room is a log of JSON-lines segments (00000001.log, ...); new records
+// are appended to the last segment, which rolls over after SegmentSize
+// records. Only the last segment of a room stays open; older segments are
+// opened by the queries that read from them. An in-memory index by ID,
+// rebuilt when a room is first accessed, points at each record, so queries
+// read only the records they return. A torn record left by a crash is cut off
+// when the room is opened.
+//
+// Completing a stored message appends a new copy of it. Once the superseded
+// copies of a room reach a full segment and outnumber the live records, the
+// room is compacted: live records are rewritten into fresh segments and the
+// old ones are removed.
+type FileStore struct {
+	dir         string
+	segmentSize int
+
+	mu     sync.Mutex
+	rooms  map[string]*roomLog
+	closed bool
+}
+
+var _ MessageStore = (*FileStore)(nil)
+
+// roomLog is the open log of a single room.
+type roomLog struct {
+	dir      string
+	room     string   // Written to roomNameFile when the directory name is hashed
+	segments []string // Segment paths, oldest first
+	tail     *os.File // The last segment, open for appending
+	last     int      // Number of the last segment file
+	size     int64    // Bytes in the last segment
+	records  int      // Records in the last segment
+	dead     int      // Superseded or unreadable records in all segments
+	index    []logEntry
+}
+
+// logEntry locates a record and keeps what queries filter on.
+type logEntry struct {
+	id     int64
+	time   time.Time
+	seg    int
+	offset int64
+	length int
+}
+
+func entryID(e logEntry) int64 { return e.id }
+
+// NewFileStore opens or creates a message store in dir. segmentSize is the
+// number of records per segment file (0 = 1000).
+func NewFileStore(dir string, segmentSize int) (*FileStore, error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, segmentSize: segmentSize, rooms: make(map[string]*roomLog)}, nil
+}
+
+// roomLocked returns the log of room, opening and indexing it on first use.
+func (s *FileStore) roomLocked(room string) (*roomLog, error) {
+	if s.closed {
+		return nil, errors.New("message store closed")
+	}
+	if r, ok := s.rooms[room]; ok {
+		return r, nil
+	}
+	r, err := openRoomLog(filepath.Join(s.dir, roomDirName(room)), room)
+	if err != nil {
+		return nil, fmt.Errorf("open room %q: %w", room, err)
+	}
+	s.rooms[room] = r
+	return r, nil
+}
+
+// roomDirName returns the directory name of room.
+func roomDirName(room string) string {
+	if len(room) > maxEncodedRoom {
+		sum := sha256.Sum256([]byte(room))
+		return hashedDirPrefix + hex.EncodeToString(sum[:])
+	}
+	return roomDirPrefix + hex.EncodeToString([]byte(room))
+}
+
+// roomFromDir returns the room stored in the directory name inside the store
+// directory dir, if it is one.
+func roomFromDir(dir, name string) (string, bool) {
+	if strings.HasPrefix(name, hashedDirPrefix) {
+		room, err := os.ReadFile(filepath.Join(dir, name, roomNameFile))
+		return string(room), err == nil && roomDirName(string(room)) == name
+	}
+	encoded, ok := strings.CutPrefix(name, roomDirPrefix)
+	if !ok {
+		return "", false
+	}
+	room, err := hex.DecodeString(encoded)
+	return string(room), err == nil && len(room) <= maxEncodedRoom
+}
+
+func openRoomLog(dir, room string) (*roomLog, error) {
+	r := &roomLog{dir: dir, room: room}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".log") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	byID := make(map[int64]logEntry)
+	total := 0
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		r.segments = append(r.segments, path)
+		if err := r.scan(f, i, byID); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if i == len(names)-1 {
+			r.tail = f
+		} else if err := f.Close(); err != nil {
+			return nil, err
+		}
+		total += r.records
+		_, _ = fmt.Sscanf(name, "%08d.log", &r.last)
+	}
+	for _, e := range byID {
+		r.index = append(r.index, e)
+	}
+	r.dead = total - len(r.index)
+	slices.SortFunc(r.index, func(a, b logEntry) int { return cmp.Compare(a.id, b.id) })
+	return r, nil
+}
+
+// scan indexes a segment into byID and truncates a torn trailing record.
+// Later records replace earlier ones with the same ID.
+func (r *roomLog) scan(f *os.File, seg int, byID map[int64]logEntry) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br := bufio.NewReader(f)
+	var offset int64
+	records := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				// Incomplete record from an interrupted write
+				if err := f.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var m Message
+		if json.Unmarshal(line, &m) == nil && m.ID != 0 {
+			byID[m.ID] = logEntry{id: m.ID, time: m.Time, seg: seg, offset: offset, length: len(line)}
+		}
+		offset += int64(len(line))
+		records++
+	}
+	r.size, r.records = offset, records
+	return nil
+}
+
+// find returns the index position of id.
+func (r *roomLog) find(id int64) (int, bool) {
+	return slices.BinarySearchFunc(r.index, id, func(e logEntry, id int64) int { return cmp.Compare(e.id, id) })
+}
+
+// write appends a record to the last segment, rolling over when it is full.
+func (r *roomLog) write(m Message, segmentSize int) (logEntry, error) {
+	if r.tail == nil || r.records >= segmentSize {
+		if err := r.roll(); err != nil {
+			return logEntry{}, err
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return logEntry{}, err
+	}
+	data = append(data, '\n')
+	if _, err := r.tail.Write(data); err != nil {
+		return logEntry{}, err
+	}
+	e := logEntry{id: m.ID, time: m.Time, seg: len(r.segments) - 1, offset: r.size, length: len(data)}
+	r.size += int64(len(data))
+	r.records++
+	return e, nil
+}
+
+// roll starts a new last segment. The previous one is synced and closed, as
+// it is never written again.
+func (r *roomLog) roll() error {
+	if r.tail != nil {
+		if err := r.tail.Sync(); err != nil {
+			return err
+		}
+		if err := r.tail.Close(); err != nil {
+			return err
+		}
+		r.tail = nil
+	}
+	if len(r.segments) == 0 {
+		if err := r.create(); err != nil {
+			return err
+		}
+	}
+	name := filepath.Join(r.dir, fmt.Sprintf("%08d.log", r.last+1))
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	r.segments = append(r.segments, name)
+	r.tail = f
+	r.last++
+	r.size, r.records = 0, 0
+	return nil
+}
+
+// create makes the room directory and records the room name if the
+// directory name does not carry it.
+func (r *roomLog) create() error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(filepath.Base(r.dir), hashedDirPrefix) {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(r.dir, roomNameFile), []byte(r.room), 0o644)
+}
+
+// read loads the records of the given entries. Segments other than the last
+// are opened for the duration of the call.
+func (r *roomLog) read(entries []logEntry) ([]Message, error) {
+	opened := make(map[int]*os.File)
+	defer func() {
+		for _, f := range opened {
+			_ = f.Close()
+		}
+	}()
+	out := make([]Message, 0, len(entries))
+	for _, e := range entries {
+		f := r.tail
+		if e.seg != len(r.segments)-1 {
+			var ok bool
+			if f, ok = opened[e.seg]; !ok {
+				var err error
+				if f, err = os.Open(r.segments[e.seg]); err != nil {
+					return nil, err
+				}
+				opened[e.seg] = f
+			}
+		}
+		buf := make([]byte, e.length)
+		if _, err := f.ReadAt(buf, e.offset); err != nil {
+			return nil, err
+		}
+		var m Message
+		if err := json.Unmarshal(buf, &m); err != nil {
+			return nil, fmt.Errorf("segment %d offset %d: %w", e.seg+1, e.offset, err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// compact rewrites the live records into new segments and removes the old
+// ones. The new segments are numbered after the old ones, so if the process
+// stops halfway the next open still prefers the rewritten copies.
+func (r *roomLog) compact(segmentSize int) error {
+	live, err := r.read(r.index)
+	if err != nil {
+		return err
+	}
+	old, tail, size, records := r.segments, r.tail, r.size, r.records
+	r.segments, r.tail = nil, nil
+	index := make([]logEntry, 0, len(live))
+	err = func() error {
+		for _, m := range live {
+			e, err := r.write(m, segmentSize)
+			if err != nil {
+				return err
+			}
+			index = append(index, e)
+		}
+		if r.tail != nil {
+			return r.tail.Sync()
+		}
+		return nil
+	}()
+	if err != nil {
+		// Keep the old segments and drop the partial rewrite. The segment
+		// number keeps counting so a leftover file is never reused.
+		_ = r.close()
+		for _, name := range r.segments {
+			_ = os.Remove(name)
+		}
+		r.segments, r.tail, r.size, r.records = old, tail, size, records
+		return err
+	}
+
+	r.index, r.dead = index, 0
+	var errs []error
+	if tail != nil {
+		errs = append(errs, tail.Close())
+	}
+	for _, name := range old {
+		errs = append(errs, os.Remove(name))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *roomLog) close() error {
+	if r.tail == nil {
+		return nil
+	}
+	err := r.tail.Close()
+	r.tail = nil
+	return err
+}
+
+// Append writes messages that are not stored yet. A known ID is rewritten
+// only when the new copy fills in missing fields; the room is compacted once
+// enough copies are superseded.
+func (s *FileStore) Append(room string, msgs ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.roomLocked(room)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		if m.ID == 0 {
+			continue
+		}
+		i, found := r.find(m.ID)
+		if found {
+			stored, err := r.read(r.index[i : i+1])
+			if err != nil {
+				return err
+			}
+			merged, changed := mergeMessage(stored[0], m)
+			if !changed {
+				continue
+			}
+			m = merged
+		}
+		e, err := r.write(m, s.segmentSize)
+		if err != nil {
+			return err
+		}
+		if found {
+			r.index[i] = e
+			r.dead++
+		} else {
+			r.index = slices.Insert(r.index, i, e)
+		}
+	}
+	if r.dead >= s.segmentSize && r.dead >= len(r.index) {
+		return r.compact(s.segmentSize)
+	}
+	return nil
+}
+
+// query runs pick on the index of room and reads the selected records.
+func (s *FileStore) query(room string, pick func(index []logEntry) []logEntry) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.roomLocked(room)
+	if err != nil {
+		return nil, err
+	}
+	return r.read(pick(r.index))
+}
+
+// Latest returns the n newest messages.
+func (s *FileStore) Latest(room string, n int) ([]Message, error) {
+	return s.query(room, func(index []logEntry) []logEntry { return latestN(index, n) })
+}
+
+// Before returns up to n messages older than id, newest last.
+func (s *FileStore) Before(room string, id int64, n int) ([]Message, error) {
+	return s.query(room, func(index []logEntry) []logEntry { return beforeID(index, id, n, entryID) })
+}
+
+// After returns up to n messages newer than id, oldest first.
+func (s *FileStore) After(room string, id int64, n int) ([]Message, error) {
+	return s.query(room, func(index []logEntry) []logEntry { return afterID(index, id, n, entryID) })
+}
+
+// Range returns the messages sent in [from, to).
+func (s *FileStore) Range(room string, from, to time.Time) ([]Message, error) {
+	return s.query(room, func(index []logEntry) []logEntry {
+		var picked []logEntry
+		for _, e := range index {
+			if inRange(e.time, from, to) {
+				picked = append(picked, e)
+			}
+		}
+		return picked
+	})
+}
+
+// Rooms returns the names of all rooms with a log on disk.
+func (s *FileStore) Rooms() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var rooms []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if room, ok := roomFromDir(s.dir, e.Name()); ok {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms, nil
+}
+
+// Close closes the open segment files. The store cannot be used afterwards.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var errs []error
+	for _, r := range s.rooms {
+		errs = append(errs, r.close())
+	}
+	s.rooms = nil
+	return errors.Join(errs...)
+}
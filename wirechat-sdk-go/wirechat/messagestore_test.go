@@ -0,0 +1,195 @@
+package wirechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageStore(t *testing.T) {
+	base := time.Unix(1700000000, 0).UTC()
+	msg := func(id int64) Message {
+		return Message{ID: id, Room: "general", User: "bob", Text: fmt.Sprint("m", id), Time: base.Add(time.Duration(id) * time.Minute)}
+	}
+	ids := func(msgs []Message, err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		var out []string
+		for _, m := range msgs {
+			out = append(out, fmt.Sprint(m.ID))
+		}
+		return strings.Join(out, ",")
+	}
+
+	dir := t.TempDir()
+	fileStore, err := NewFileStore(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, store := range map[string]MessageStore{"memory": NewMemoryStore(), "file": fileStore} {
+		// Out of order, duplicated and guest messages
+		if err := store.Append("general", msg(3), msg(1), msg(5), msg(3), Message{Text: "guest"}); err != nil {
+			t.Fatalf("%s: append: %v", name, err)
+		}
+		if err := store.Append("general", msg(2), msg(4)); err != nil {
+			t.Fatalf("%s: append: %v", name, err)
+		}
+		for query, got := range map[string]string{
+			"latest": ids(store.Latest("general", 2)),
+			"before": ids(store.Before("general", 4, 2)),
+			"after":  ids(store.After("general", 2, 10)),
+			"range":  ids(store.Range("general", base.Add(2*time.Minute), base.Add(4*time.Minute))),
+			"empty":  ids(store.Latest("random", 5)),
+		} {
+			want := map[string]string{"latest": "4,5", "before": "2,3", "after": "3,4,5", "range": "2,3", "empty": ""}[query]
+			if got != want {
+				t.Errorf("%s: %s = %q, want %q", name, query, got, want)
+			}
+		}
+
+		// A REST copy fills in the room ID of a message first seen over WebSocket
+		withID := msg(5)
+		withID.RoomID = 7
+		if err := store.Append("general", withID); err != nil {
+			t.Fatalf("%s: merge: %v", name, err)
+		}
+		if got, _ := store.Latest("general", 1); got[0].RoomID != 7 {
+			t.Errorf("%s: merge lost room ID: %+v", name, got[0])
+		}
+	}
+
+	// The file store survives a reopen, including a torn trailing record
+	if err := fileStore.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(dir+"/"+roomDirName("general")+"/00000003.log", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"id":9,"room":"gen`)
+	_ = f.Close()
+	fileStore, err = NewFileStore(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+	if got := ids(fileStore.Latest("general", 10)); got != "1,2,3,4,5" {
+		t.Fatalf("reopened store = %q", got)
+	}
+	if err := fileStore.Append("general", msg(6)); err != nil {
+		t.Fatalf("append after reopen: %v", err)
+	}
+
+	// The client feeds the store from events and REST pages
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rooms":
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"id": 7, "name": "general"}})
+		case "/rooms/7/messages":
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": 11, "room_id": 7, "user": "ann", "body": "old"}}})
+		}
+	}))
+	defer api.Close()
+	cfg := DefaultConfig()
+	cfg.RESTBaseURL = api.URL
+	cfg.MessageStore = NewMemoryStore()
+	c := NewClient(&cfg)
+	raw, _ := json.Marshal(HistoryEvent{Room: "general", Messages: []MessageEvent{{ID: 12, Room: "general", User: "bob", Text: "hi", TS: base.Unix()}}})
+	c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: eventHistory, Data: raw})
+	if _, err := c.REST.ListRooms(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.REST.GetMessages(context.Background(), 7, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := cfg.MessageStore.Latest("general", 10)
+	if len(got) != 2 || got[0].Text != "old" || got[1].Text != "hi" || !got[1].Time.Equal(base) {
+		t.Fatalf("unexpected cached messages: %+v", got)
+	}
+}
+
+func TestFileStoreRooms(t *testing.T) {
+	root := t.TempDir()
+	dir := root + "/store"
+	store, err := NewFileStore(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Any room name stays a single directory inside the store
+	names := []string{"..", ".", "", "a/b", "../escape", strings.Repeat("long room ", 30)}
+	for i, room := range names {
+		if err := store.Append(room, Message{ID: int64(i + 1), Text: room}); err != nil {
+			t.Fatalf("append %q: %v", room, err)
+		}
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 1 {
+		t.Fatalf("store wrote outside its directory: %v", entries)
+	}
+	rooms, err := store.Rooms()
+	if err != nil || len(rooms) != len(names) {
+		t.Fatalf("rooms = %q (%v)", rooms, err)
+	}
+	for i, room := range names {
+		if got, _ := store.Latest(room, 1); len(got) != 1 || got[0].ID != int64(i+1) {
+			t.Errorf("room %q = %+v", room, got)
+		}
+	}
+
+	// Completing stored messages compacts the superseded copies away
+	var msgs []Message
+	for id := int64(1); id <= 10; id++ {
+		msgs = append(msgs, Message{ID: id, Text: fmt.Sprint("m", id)})
+	}
+	if err := store.Append("general", msgs...); err != nil {
+		t.Fatal(err)
+	}
+	for i := range msgs {
+		msgs[i].RoomID = 7
+	}
+	if err := store.Append("general", msgs...); err != nil {
+		t.Fatal(err)
+	}
+	segments, _ := os.ReadDir(dir + "/" + roomDirName("general"))
+	lines := 0
+	for _, seg := range segments {
+		data, _ := os.ReadFile(dir + "/" + roomDirName("general") + "/" + seg.Name())
+		lines += bytes.Count(data, []byte("\n"))
+	}
+	if lines != len(msgs) {
+		t.Fatalf("expected %d records after compaction, got %d in %d segments", len(msgs), lines, len(segments))
+	}
+	if got, _ := store.Latest("general", 10); len(got) != 10 || got[0].RoomID != 7 || got[9].Text != "m10" {
+		t.Fatalf("compacted room = %+v", got)
+	}
+	if err := store.Append("general", Message{ID: 11}); err != nil {
+		t.Fatalf("append after compaction: %v", err)
+	}
+
+	// A reopened store finds every room, including the hashed long name, and
+	// reads records from segments that are no longer open
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewFileStore(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if rooms, err := store.Rooms(); err != nil || !slices.Contains(rooms, names[len(names)-1]) || len(rooms) != len(names)+1 {
+		t.Fatalf("rooms after reopen = %q (%v)", rooms, err)
+	}
+	if got, _ := store.After("general", 0, 20); len(got) != 11 || got[0].Text != "m1" || got[10].ID != 11 {
+		t.Fatalf("reopened room = %+v", got)
+	}
+}
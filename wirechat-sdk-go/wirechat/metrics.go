@@ -0,0 +1,60 @@
+package wirechat
+
+import (
+	"errors"
+	"time"
+)
+
+// Metrics receives SDK instrumentation. Implementations must be safe for
+// concurrent use. See the metrics subpackage for an in-memory implementation
+// with a Prometheus exporter.
+type Metrics interface {
+	// FrameSent counts a frame written to the socket, by inbound type.
+	FrameSent(frameType string)
+	// FrameReceived counts a frame read from the socket, by event name or outbound type.
+	FrameReceived(frameType string)
+	// DispatchLatency observes how long handlers took for an incoming frame.
+	DispatchLatency(frameType string, d time.Duration)
+	// ReconnectAttempt counts a reconnect attempt.
+	ReconnectAttempt(attempt int)
+	// ReconnectDuration observes how long a reconnect attempt took, including backoff.
+	ReconnectDuration(d time.Duration, success bool)
+	// BufferDepth reports the number of messages in the outgoing buffer.
+	BufferDepth(depth int)
+	// Error counts an error reported to OnError.
+	Error(code ErrorCode)
+	// RESTRequest observes a REST call by endpoint template and HTTP status (0 = no response).
+	RESTRequest(endpoint string, status int, d time.Duration)
+	// StateDuration observes time spent in a connection state before leaving it.
+	StateDuration(state ConnectionState, d time.Duration)
+}
+
+// noopMetrics discards all metrics.
+type noopMetrics struct{}
+
+func (noopMetrics) FrameSent(string)                             {}
+func (noopMetrics) FrameReceived(string)                         {}
+func (noopMetrics) DispatchLatency(string, time.Duration)        {}
+func (noopMetrics) ReconnectAttempt(int)                         {}
+func (noopMetrics) ReconnectDuration(time.Duration, bool)        {}
+func (noopMetrics) BufferDepth(int)                              {}
+func (noopMetrics) Error(ErrorCode)                              {}
+func (noopMetrics) RESTRequest(string, int, time.Duration)       {}
+func (noopMetrics) StateDuration(ConnectionState, time.Duration) {}
+
+// errorCode extracts the ErrorCode of an error, ErrorUnknown if it is not a WirechatError.
+func errorCode(err error) ErrorCode {
+	var we *WirechatError
+	if errors.As(err, &we) {
+		return we.Code
+	}
+	return ErrorUnknown
+}
+
+// frameLabel names an incoming frame for metrics: the event name for events, the type otherwise.
+func frameLabel(out Outbound) string {
+	if out.Type == outboundEvent && out.Event != "" {
+		return out.Event
+	}
+	return out.Type
+}
@@ -0,0 +1,200 @@
+// Package metrics provides an in-memory wirechat.Metrics implementation
+// and a Prometheus text-format exporter.
+package metrics
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat"
+)
+
+// Metric names exported by Memory.
+const (
+	FramesSentTotal        = "wirechat_frames_sent_total"
+	FramesReceivedTotal    = "wirechat_frames_received_total"
+	DispatchDuration       = "wirechat_dispatch_duration_seconds"
+	ReconnectAttemptsTotal = "wirechat_reconnect_attempts_total"
+	ReconnectDuration      = "wirechat_reconnect_duration_seconds"
+	BufferDepth            = "wirechat_buffer_depth"
+	ErrorsTotal            = "wirechat_errors_total"
+	RESTRequestDuration    = "wirechat_rest_request_duration_seconds"
+	StateDurationTotal     = "wirechat_state_duration_seconds_total"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+// family is a named metric with a fixed set of label names.
+type family struct {
+	name   string
+	help   string
+	kind   kind
+	labels []string
+	series map[string]*series // keyed by joined label values
+}
+
+// series is a single labeled time series.
+type series struct {
+	values  []string
+	value   float64  // counter or gauge value
+	buckets []uint64 // cumulative histogram bucket counts
+	sum     float64
+	count   uint64
+}
+
+// Memory is a concurrency-safe, in-memory wirechat.Metrics implementation.
+type Memory struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+var _ wirechat.Metrics = (*Memory)(nil)
+
+// NewMemory creates an empty in-memory metrics registry using DefaultBuckets.
+func NewMemory() *Memory {
+	m := &Memory{
+		buckets:  DefaultBuckets,
+		families: make(map[string]*family),
+	}
+	m.register(FramesSentTotal, "Frames written to the socket by type.", kindCounter, "type")
+	m.register(FramesReceivedTotal, "Frames read from the socket by type.", kindCounter, "type")
+	m.register(DispatchDuration, "Time spent in event handlers by frame type.", kindHistogram, "type")
+	m.register(ReconnectAttemptsTotal, "Reconnect attempts.", kindCounter)
+	m.register(ReconnectDuration, "Duration of reconnect attempts including backoff.", kindHistogram, "result")
+	m.register(BufferDepth, "Messages waiting in the outgoing buffer.", kindGauge)
+	m.register(ErrorsTotal, "Errors reported to OnError by code.", kindCounter, "code")
+	m.register(RESTRequestDuration, "REST request duration by endpoint and status.", kindHistogram, "endpoint", "status")
+	m.register(StateDurationTotal, "Time spent in each connection state.", kindCounter, "state")
+	return m
+}
+
+func (m *Memory) register(name, help string, k kind, labels ...string) {
+	m.families[name] = &family{
+		name:   name,
+		help:   help,
+		kind:   k,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+// FrameSent implements wirechat.Metrics.
+func (m *Memory) FrameSent(frameType string) { m.add(FramesSentTotal, 1, frameType) }
+
+// FrameReceived implements wirechat.Metrics.
+func (m *Memory) FrameReceived(frameType string) { m.add(FramesReceivedTotal, 1, frameType) }
+
+// DispatchLatency implements wirechat.Metrics.
+func (m *Memory) DispatchLatency(frameType string, d time.Duration) {
+	m.observe(DispatchDuration, d, frameType)
+}
+
+// ReconnectAttempt implements wirechat.Metrics.
+func (m *Memory) ReconnectAttempt(int) { m.add(ReconnectAttemptsTotal, 1) }
+
+// ReconnectDuration implements wirechat.Metrics.
+func (m *Memory) ReconnectDuration(d time.Duration, success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	m.observe(ReconnectDuration, d, result)
+}
+
+// BufferDepth implements wirechat.Metrics.
+func (m *Memory) BufferDepth(depth int) { m.set(BufferDepth, float64(depth)) }
+
+// Error implements wirechat.Metrics.
+func (m *Memory) Error(code wirechat.ErrorCode) { m.add(ErrorsTotal, 1, code.String()) }
+
+// RESTRequest implements wirechat.Metrics.
+func (m *Memory) RESTRequest(endpoint string, status int, d time.Duration) {
+	m.observe(RESTRequestDuration, d, endpoint, strconv.Itoa(status))
+}
+
+// StateDuration implements wirechat.Metrics.
+func (m *Memory) StateDuration(state wirechat.ConnectionState, d time.Duration) {
+	m.add(StateDurationTotal, d.Seconds(), state.String())
+}
+
+// Value returns the current value of a counter or gauge series.
+func (m *Memory) Value(name string, labels ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.lookup(name, labels); s != nil {
+		return s.value
+	}
+	return 0
+}
+
+// Count returns the number of observations and their sum for a histogram series.
+func (m *Memory) Count(name string, labels ...string) (count uint64, sum float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.lookup(name, labels); s != nil {
+		return s.count, s.sum
+	}
+	return 0, 0
+}
+
+func (m *Memory) add(name string, delta float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesLocked(name, labels).value += delta
+}
+
+func (m *Memory) set(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesLocked(name, labels).value = value
+}
+
+func (m *Memory) observe(name string, d time.Duration, labels ...string) {
+	v := d.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.seriesLocked(name, labels)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(m.buckets))
+	}
+	for i, le := range m.buckets {
+		if v <= le {
+			s.buckets[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (m *Memory) lookup(name string, labels []string) *series {
+	f, ok := m.families[name]
+	if !ok {
+		return nil
+	}
+	return f.series[strings.Join(labels, "\xff")]
+}
+
+func (m *Memory) seriesLocked(name string, labels []string) *series {
+	f := m.families[name]
+	key := strings.Join(labels, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(labels)}
+		f.series[key] = s
+	}
+	return s
+}
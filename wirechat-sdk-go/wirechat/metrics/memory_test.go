@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+func TestMemoryPrometheus(t *testing.T) {
+	m := NewMemory()
+	m.FrameSent("msg")
+	m.FrameSent("msg")
+	m.Error(wirechat.ErrorRateLimited)
+	m.RESTRequest("/rooms/{id}/messages", 200, 20*time.Millisecond)
+
+	if got := m.Value(FramesSentTotal, "msg"); got != 2 {
+		t.Fatalf("expected 2 frames sent, got %v", got)
+	}
+	if count, _ := m.Count(RESTRequestDuration, "/rooms/{id}/messages", "200"); count != 1 {
+		t.Fatalf("expected 1 REST observation, got %d", count)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WritePrometheus(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`wirechat_frames_sent_total{type="msg"} 2`,
+		`wirechat_errors_total{code="rate_limited"} 1`,
+		`wirechat_rest_request_duration_seconds_bucket{endpoint="/rooms/{id}/messages",status="200",le="0.025"} 1`,
+		`wirechat_rest_request_duration_seconds_count{endpoint="/rooms/{id}/messages",status="200"} 1`,
+		"# TYPE wirechat_buffer_depth gauge",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
+
+// spanRecorder is a trace.Tracer that records the names of started spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, _ ...trace.Attribute) (context.Context, trace.Span) {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+	return trace.Noop{}.Start(ctx, name)
+}
+
+func (r *spanRecorder) Inject(context.Context, http.Header) {}
+
+func (r *spanRecorder) spans() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.names)
+}
+
+func TestClientMetrics(t *testing.T) {
+	m := NewMemory()
+	tracer := &spanRecorder{}
+	mem := transport.NewMemory()
+	cfg := wirechat.DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.Transport = mem
+	cfg.Metrics = m
+	cfg.Tracer = tracer
+	c := wirechat.NewClient(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	received := make(chan wirechat.MessageEvent, 1)
+	c.OnMessage(func(ev wirechat.MessageEvent) { received <- ev })
+
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	server, err := mem.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Join(ctx, "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if err := c.Send(ctx, "general", "hi"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	for range 3 { // hello, join, msg
+		if _, _, err := server.ReadFrame(ctx); err != nil {
+			t.Fatalf("server read: %v", err)
+		}
+	}
+	frame := `{"type":"event","event":"message","data":{"id":1,"room":"general","user":"bob","text":"hi","ts":1}}`
+	if err := server.WriteFrame(ctx, transport.MessageText, []byte(frame)); err != nil {
+		t.Fatalf("server write: %v", err)
+	}
+	select {
+	case <-received:
+	case <-ctx.Done():
+		t.Fatal("message not received")
+	}
+
+	// Sent frames are counted by the write loop after the server has read them
+	for m.Value(FramesSentTotal, "msg") == 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	for _, typ := range []string{"hello", "join", "msg"} {
+		if got := m.Value(FramesSentTotal, typ); got != 1 {
+			t.Errorf("frames sent %s = %v, want 1", typ, got)
+		}
+	}
+	if got := m.Value(FramesReceivedTotal, "message"); got != 1 {
+		t.Errorf("frames received = %v, want 1", got)
+	}
+	if count, _ := m.Count(DispatchDuration, "message"); count != 1 {
+		t.Errorf("dispatch observations = %d, want 1", count)
+	}
+	if got := m.Value(StateDurationTotal, wirechat.StateConnecting.String()); got <= 0 {
+		t.Errorf("expected time spent connecting, got %v", got)
+	}
+
+	spans := tracer.spans()
+	for _, want := range []string{"wirechat.connect", "wirechat.join", "wirechat.send"} {
+		if !slices.Contains(spans, want) {
+			t.Errorf("missing span %s in %v", want, spans)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format.
+// The metrics are rendered under the lock and written afterwards, so a slow
+// writer such as a scrape connection does not block metric updates.
+func (m *Memory) WritePrometheus(w io.Writer) error {
+	var buf bytes.Buffer
+	m.render(&buf)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// render formats all metrics into buf.
+func (m *Memory) render(bw *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		f := m.families[name]
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != kindHistogram {
+				fmt.Fprintf(bw, "%s%s %s\n", f.name, formatLabels(f.labels, s.values, ""), formatFloat(s.value))
+				continue
+			}
+			for i, le := range m.buckets {
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, formatFloat(le)), s.buckets[i])
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, "+Inf"), s.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.values, ""), formatFloat(s.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.values, ""), s.count)
+		}
+	}
+}
+
+// Handler returns an http.Handler serving the metrics in Prometheus text format.
+func (m *Memory) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
+
+// formatLabels renders a label set, adding the le label for histogram buckets.
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	if le != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`le="`)
+		b.WriteString(le)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,308 @@
+package wirechat
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultWriteQueueSize is used when Config.WriteQueueSize is not positive.
+const defaultWriteQueueSize = 16
+
+// Lane is a write priority class. Lower lanes are written first.
+type Lane int
+
+const (
+	// LaneControl carries join and leave frames.
+	LaneControl Lane = iota
+
+	// LaneMessage carries chat messages.
+	LaneMessage
+
+	// LaneTyping carries typing indicators.
+	LaneTyping
+
+	// LanePresence carries presence updates.
+	LanePresence
+
+	laneCount
+)
+
+// String returns the string representation of a Lane.
+func (l Lane) String() string {
+	switch l {
+	case LaneControl:
+		return "control"
+	case LaneMessage:
+		return "message"
+	case LaneTyping:
+		return "typing"
+	case LanePresence:
+		return "presence"
+	default:
+		return "unknown"
+	}
+}
+
+// laneFor classifies a frame type into a write lane.
+func laneFor(frameType string) Lane {
+	switch frameType {
+	case inboundHello, inboundJoin, inboundLeave:
+		return LaneControl
+	case "typing":
+		return LaneTyping
+	case "presence":
+		return LanePresence
+	default:
+		return LaneMessage
+	}
+}
+
+// Fairness selects how the writer schedules lanes.
+type Fairness int
+
+const (
+	// FairnessStrict always drains higher priority lanes first.
+	FairnessStrict Fairness = iota
+
+	// FairnessWeighted serves lanes in priority order, writing at most
+	// LaneWeights[lane] frames per lane in each round so lower lanes cannot starve.
+	FairnessWeighted
+)
+
+// String returns the string representation of a Fairness.
+func (f Fairness) String() string {
+	switch f {
+	case FairnessStrict:
+		return "strict"
+	case FairnessWeighted:
+		return "weighted"
+	default:
+		return "unknown"
+	}
+}
+
+// defaultLaneWeights returns the per-round frame budget for weighted fairness.
+func defaultLaneWeights() map[Lane]int {
+	return map[Lane]int{
+		LaneControl:  8,
+		LaneMessage:  4,
+		LaneTyping:   1,
+		LanePresence: 1,
+	}
+}
+
+// writeQueue holds one FIFO channel per lane.
+// Order is preserved within a lane; frames in different lanes may be reordered.
+type writeQueue struct {
+	lanes    [laneCount]chan outgoing
+	weights  [laneCount]int
+	weighted bool
+
+	carryMu sync.Mutex
+	carried *outgoing // Frame taken by a cancelled write loop, written first by the next one
+}
+
+func newWriteQueue(cfg *Config) *writeQueue {
+	size := cfg.WriteQueueSize
+	if size <= 0 {
+		size = defaultWriteQueueSize
+	}
+
+	q := &writeQueue{weighted: cfg.WriteFairness == FairnessWeighted}
+	for l := range laneCount {
+		q.lanes[l] = make(chan outgoing, size)
+		q.weights[l] = 1
+		if w := cfg.LaneWeights[l]; w > 0 {
+			q.weights[l] = w
+		}
+	}
+	return q
+}
+
+// lane returns the channel for a frame type.
+func (q *writeQueue) lane(frameType string) chan outgoing {
+	return q.lanes[laneFor(frameType)]
+}
+
+// capacity returns the capacity of a single lane.
+func (q *writeQueue) capacity() int {
+	return cap(q.lanes[LaneControl])
+}
+
+// next returns the next frame to write, honoring lane priority and fairness.
+// credits holds the remaining per-lane budget of the current round and is
+// owned by the calling write loop.
+func (q *writeQueue) next(ctx context.Context, credits *[laneCount]int) (outgoing, bool) {
+	if out, ok := q.takeCarried(); ok {
+		return out, true
+	}
+	if out, ok := q.poll(credits); ok {
+		return out, true
+	}
+
+	// All lanes with pending frames are out of budget: start a new round
+	if q.weighted {
+		*credits = q.weights
+		if out, ok := q.poll(credits); ok {
+			return out, true
+		}
+	}
+
+	// Nothing queued, wait for the first frame in any lane
+	var out outgoing
+	select {
+	case out = <-q.lanes[LaneControl]:
+	case out = <-q.lanes[LaneMessage]:
+	case out = <-q.lanes[LaneTyping]:
+	case out = <-q.lanes[LanePresence]:
+	case <-ctx.Done():
+		return outgoing{}, false
+	}
+	if q.weighted {
+		credits[laneFor(out.in.Type)]--
+	}
+	return out, true
+}
+
+// carry keeps a frame taken by a write loop that is shutting down.
+func (q *writeQueue) carry(out outgoing) {
+	q.carryMu.Lock()
+	q.carried = &out
+	q.carryMu.Unlock()
+}
+
+func (q *writeQueue) takeCarried() (outgoing, bool) {
+	q.carryMu.Lock()
+	defer q.carryMu.Unlock()
+	if q.carried == nil {
+		return outgoing{}, false
+	}
+	out := *q.carried
+	q.carried = nil
+	return out, true
+}
+
+// drain removes every queued frame, including a carried one.
+func (q *writeQueue) drain() []outgoing {
+	var frames []outgoing
+	if out, ok := q.takeCarried(); ok {
+		frames = append(frames, out)
+	}
+	for _, lane := range q.lanes {
+		for len(lane) > 0 {
+			select {
+			case out := <-lane:
+				frames = append(frames, out)
+			default:
+			}
+		}
+	}
+	return frames
+}
+
+// poll takes a queued frame from the highest priority lane with budget left.
+func (q *writeQueue) poll(credits *[laneCount]int) (outgoing, bool) {
+	for l := range laneCount {
+		if q.weighted && credits[l] <= 0 {
+			continue
+		}
+		select {
+		case out := <-q.lanes[l]:
+			if q.weighted {
+				credits[l]--
+			}
+			return out, true
+		default:
+		}
+	}
+	return outgoing{}, false
+}
+
+// OverflowPolicy controls what happens when the write queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the queue has room or the context is done.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued frame to make room.
+	// Join and leave frames are never discarded; the control lane blocks instead.
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the frame being sent and returns ErrorQueueFull.
+	OverflowDropNewest
+
+	// OverflowError rejects the frame being sent with ErrorQueueFull.
+	OverflowError
+)
+
+// String returns the string representation of an OverflowPolicy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// push puts a frame into its lane according to the configured overflow policy.
+func (c *Client) push(ctx context.Context, out outgoing) error {
+	ch := c.queue.lane(out.in.Type)
+
+	policy := c.config().WriteQueuePolicy
+	if policy == OverflowDropOldest && laneFor(out.in.Type) == LaneControl {
+		// Losing a join or leave would desync room membership
+		policy = OverflowBlock
+	}
+
+	switch policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case ch <- out:
+				return nil
+			default:
+			}
+			select {
+			case dropped := <-ch:
+				dropped.delivery.fail(NewError(ErrorQueueFull, "dropped from full write queue"))
+				c.logger.Warn("write queue full, dropped oldest frame", map[string]any{"type": dropped.in.Type})
+			default:
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	case OverflowDropNewest:
+		select {
+		case ch <- out:
+		default:
+			err := NewError(ErrorQueueFull, "dropped from full write queue")
+			out.delivery.fail(err)
+			c.logger.Warn("write queue full, dropped newest frame", map[string]any{"type": out.in.Type})
+			return err
+		}
+		return nil
+	case OverflowError:
+		select {
+		case ch <- out:
+			return nil
+		default:
+			return NewError(ErrorQueueFull, "write queue full")
+		}
+	default:
+		select {
+		case ch <- out:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
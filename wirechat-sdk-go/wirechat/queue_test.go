@@ -0,0 +1,123 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteQueueOverflow(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WriteQueueSize = 1
+	cfg.WriteQueuePolicy = OverflowError
+	c := NewClient(&cfg)
+	c.connected = true
+
+	if err := c.Send(context.Background(), "general", "first"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := c.Send(context.Background(), "general", "second")
+	if !errors.Is(err, NewError(ErrorQueueFull, "")) {
+		t.Fatalf("expected queue_full error, got %v", err)
+	}
+
+	cfg.WriteQueuePolicy = OverflowDropOldest
+	c = NewClient(&cfg)
+	c.connected = true
+
+	first, _ := c.SendWithDelivery(context.Background(), "general", "first")
+	if _, err := c.SendWithDelivery(context.Background(), "general", "second"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Status() != DeliveryFailed {
+		t.Fatalf("expected oldest frame to be dropped, got %s", first.Status())
+	}
+	if out := <-c.queue.lane(inboundMsg); out.in.Data.(MsgPayload).Text != "second" {
+		t.Fatalf("unexpected queued frame: %+v", out)
+	}
+}
+
+func TestWriteQueueDropPolicies(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WriteQueueSize = 1
+	cfg.WriteQueuePolicy = OverflowDropNewest
+	c := NewClient(&cfg)
+	c.connected = true
+
+	if err := c.Send(context.Background(), "general", "first"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Send(context.Background(), "general", "second"); !errors.Is(err, NewError(ErrorQueueFull, "")) {
+		t.Fatalf("expected queue_full for a dropped frame, got %v", err)
+	}
+
+	// Join and leave frames are never evicted; the control lane blocks instead
+	cfg.WriteQueuePolicy = OverflowDropOldest
+	c = NewClient(&cfg)
+	c.connected = true
+	if err := c.Join(context.Background(), "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Join(ctx, "random"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second join to block, got %v", err)
+	}
+	if out := <-c.queue.lane(inboundJoin); out.in.Data.(JoinPayload).Room != "general" {
+		t.Fatalf("queued join was evicted: %+v", out)
+	}
+
+	// A buffer flush stops and keeps its messages once the write loop is gone
+	cfg.BufferMessages = true
+	c = NewClient(&cfg)
+	for _, text := range []string{"a", "b", "c"} {
+		if err := c.Send(context.Background(), "general", text); err != nil {
+			t.Fatalf("buffer: %v", err)
+		}
+	}
+	c.writeDone = make(chan struct{})
+	close(c.writeDone)
+	if err := c.flushBuffer(context.Background()); !errors.Is(err, NewError(ErrorDisconnected, "")) {
+		t.Fatalf("expected flush to stop, got %v", err)
+	}
+	queued := len(c.queue.lane(inboundMsg))
+	if queued+len(c.messageBuffer) != 3 {
+		t.Fatalf("lost buffered messages: %d queued, %d buffered", queued, len(c.messageBuffer))
+	}
+}
+
+func TestWriteQueueLanes(t *testing.T) {
+	msg := outgoing{in: Inbound{Type: inboundMsg}}
+	join := outgoing{in: Inbound{Type: inboundJoin}}
+
+	cfg := DefaultConfig()
+	q := newWriteQueue(&cfg)
+	var credits [laneCount]int
+	q.lane(inboundMsg) <- msg
+	q.lane(inboundJoin) <- join
+	if out, _ := q.next(context.Background(), &credits); out.in.Type != inboundJoin {
+		t.Fatalf("expected control frame first, got %s", out.in.Type)
+	}
+
+	cfg.WriteFairness = FairnessWeighted
+	cfg.LaneWeights = map[Lane]int{LaneControl: 1, LaneMessage: 1}
+	q = newWriteQueue(&cfg)
+	credits = [laneCount]int{}
+	q.lane(inboundJoin) <- join
+	q.lane(inboundJoin) <- join
+	q.lane(inboundMsg) <- msg
+	q.lane(inboundMsg) <- msg
+
+	var order []string
+	for range 4 {
+		out, _ := q.next(context.Background(), &credits)
+		order = append(order, out.in.Type)
+	}
+	want := []string{inboundJoin, inboundMsg, inboundJoin, inboundMsg}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected weighted order: %v", order)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package wirechat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ReadStore persists the last-read message ID of each room for UnreadTracker.
+type ReadStore interface {
+	// Load returns the saved markers keyed by room name.
+	Load() (map[string]int64, error)
+	// Save records messageID as the last message read in room.
+	Save(room string, messageID int64) error
+}
+
+// MemoryReadStore keeps read markers in memory. It is the default store.
+type MemoryReadStore struct {
+	mu      sync.Mutex
+	markers map[string]int64
+}
+
+// NewMemoryReadStore returns an empty in-memory store.
+func NewMemoryReadStore() *MemoryReadStore {
+	return &MemoryReadStore{markers: make(map[string]int64)}
+}
+
+// Load returns a copy of the stored markers.
+func (s *MemoryReadStore) Load() (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.markers), nil
+}
+
+// Save stores the marker for room.
+func (s *MemoryReadStore) Save(room string, messageID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markers[room] = messageID
+	return nil
+}
+
+// FileReadStore keeps read markers in a JSON file. Writes go to a temporary
+// file that replaces the original, so a crash never leaves a torn file.
+type FileReadStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileReadStore returns a store backed by the JSON file at path. The file
+// and its directory are created on the first Save.
+func NewFileReadStore(path string) *FileReadStore {
+	return &FileReadStore{path: path}
+}
+
+// Load reads the markers from the file. A missing file yields no markers.
+func (s *FileReadStore) Load() (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadLocked()
+}
+
+func (s *FileReadStore) loadLocked() (map[string]int64, error) {
+	markers := make(map[string]int64)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return markers, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &markers); err != nil {
+		return nil, err
+	}
+	return markers, nil
+}
+
+// Save updates the marker for room and rewrites the file.
+func (s *FileReadStore) Save(room string, messageID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	markers, err := s.loadLocked()
+	if err != nil {
+		return err
+	}
+	markers[room] = messageID
+	data, err := json.MarshalIndent(markers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
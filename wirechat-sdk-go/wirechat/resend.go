@@ -0,0 +1,327 @@
+package wirechat
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// inflightTTL bounds how long a written frame is considered in flight.
+// The server does not correlate errors with requests, so stale entries would
+// otherwise be blamed for unrelated rate_limited errors.
+const inflightTTL = 30 * time.Second
+
+// outgoing is a frame queued for the write loop.
+type outgoing struct {
+	in       Inbound
+	attempt  int       // Number of previous attempts rejected with rate_limited
+	delivery *Delivery // Optional delivery handle for msg frames
+}
+
+// inflightMsg is a frame written to the socket but not yet answered. Only msg
+// frames carry a payload; join, leave and custom frames are kept too, so an
+// error they caused is not blamed on a message.
+type inflightMsg struct {
+	frameType string
+	room      string
+	payload   MsgPayload
+	attempt   int
+	delivery  *Delivery
+	sentAt    time.Time
+}
+
+// resender tracks in-flight frames and re-queues messages rejected with
+// rate_limited, preserving per-room order.
+type resender struct {
+	mu       sync.Mutex
+	inflight []inflightMsg
+	pending  map[string][]outgoing // Messages waiting for resend, per room
+	active   map[string]bool       // Rooms with a scheduled resend
+	gen      int                   // Bumped by reset to stop scheduled resends
+}
+
+func newResender() *resender {
+	return &resender{
+		pending: make(map[string][]outgoing),
+		active:  make(map[string]bool),
+	}
+}
+
+// track records a frame that was written to the socket.
+func (r *resender) track(out outgoing) {
+	m := inflightMsg{frameType: out.in.Type, attempt: out.attempt, delivery: out.delivery, sentAt: time.Now()}
+	switch data := out.in.Data.(type) {
+	case MsgPayload:
+		m.room, m.payload = data.Room, data
+	case JoinPayload:
+		m.room = data.Room
+	}
+
+	r.mu.Lock()
+	r.pruneLocked(m.sentAt)
+	r.inflight = append(r.inflight, m)
+	r.mu.Unlock()
+}
+
+// confirm removes and returns the oldest in-flight message matching an
+// echoed message event. The echo must come from user; an empty user (JWT
+// sessions without Config.User) matches any author.
+func (r *resender) confirm(ev MessageEvent, user string) (inflightMsg, bool) {
+	if user != "" && ev.User != user {
+		return inflightMsg{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.inflight, func(m inflightMsg) bool {
+		return m.frameType == inboundMsg && m.payload.Room == ev.Room && m.payload.Text == ev.Text
+	})
+	if i < 0 {
+		return inflightMsg{}, false
+	}
+	return r.answerLocked(i), true
+}
+
+// answer removes the oldest in-flight frame of frameType for room, e.g. a
+// join answered by the room history.
+func (r *resender) answer(frameType, room string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := slices.IndexFunc(r.inflight, func(m inflightMsg) bool { return m.frameType == frameType && m.room == room }); i >= 0 {
+		r.answerLocked(i)
+	}
+}
+
+// answerLocked removes the frame at i. The server handles frames in order,
+// so join, leave and custom frames written before it have been handled too.
+func (r *resender) answerLocked(i int) inflightMsg {
+	m := r.inflight[i]
+	kept := slices.DeleteFunc(slices.Clone(r.inflight[:i]), func(m inflightMsg) bool { return m.frameType != inboundMsg })
+	r.inflight = append(kept, r.inflight[i+1:]...)
+	return m
+}
+
+// attribute pops the oldest in-flight frame. The server handles frames
+// sequentially, so an error belongs to the earliest unanswered frame. The
+// frame is returned only if it is a msg frame.
+func (r *resender) attribute() (inflightMsg, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneLocked(time.Now())
+	if len(r.inflight) == 0 {
+		return inflightMsg{}, false
+	}
+	m := r.inflight[0]
+	r.inflight = r.inflight[1:]
+	return m, m.frameType == inboundMsg
+}
+
+// hold queues a message behind pending resends for the same room.
+// It returns false if the room has no pending resends.
+func (r *resender) hold(out outgoing) bool {
+	payload, ok := out.in.Data.(MsgPayload)
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending[payload.Room]) == 0 {
+		return false
+	}
+	r.pending[payload.Room] = append(r.pending[payload.Room], out)
+	return true
+}
+
+// requeue adds a rejected message to the room's pending queue. It returns
+// the reset generation and true if the caller must schedule a flush for the room.
+func (r *resender) requeue(room string, out outgoing) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[room] = append(r.pending[room], out)
+	if r.active[room] {
+		return r.gen, false
+	}
+	r.active[room] = true
+	return r.gen, true
+}
+
+// head returns the first pending message for a room. It returns false once
+// the queue is empty or a reset has superseded generation gen.
+func (r *resender) head(room string, gen int) (outgoing, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gen != r.gen {
+		return outgoing{}, false
+	}
+	q := r.pending[room]
+	if len(q) == 0 {
+		delete(r.pending, room)
+		delete(r.active, room)
+		return outgoing{}, false
+	}
+	return q[0], true
+}
+
+// pop removes the first pending message for a room once it has been queued for writing.
+func (r *resender) pop(room string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if q := r.pending[room]; len(q) > 0 {
+		r.pending[room] = q[1:]
+	}
+}
+
+// reset forgets in-flight frames, whose outcome is unknown after a
+// disconnect, and stops scheduled resends. It returns the in-flight frames and
+// the messages that were waiting for a resend.
+func (r *resender) reset() ([]inflightMsg, []outgoing) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var held []outgoing
+	for _, q := range r.pending {
+		held = append(held, q...)
+	}
+	inflight := r.inflight
+	r.inflight = nil
+	r.pending = make(map[string][]outgoing)
+	r.active = make(map[string]bool)
+	r.gen++
+	return inflight, held
+}
+
+// pruneLocked drops frames that stayed unanswered for inflightTTL and fails
+// their deliveries.
+func (r *resender) pruneLocked(now time.Time) {
+	i := 0
+	for i < len(r.inflight) && now.Sub(r.inflight[i].sentAt) > inflightTTL {
+		r.inflight[i].delivery.fail(NewError(ErrorTimeout, "no confirmation from server"))
+		i++
+	}
+	r.inflight = r.inflight[i:]
+}
+
+// resetResend drops in-flight bookkeeping after the connection is lost or
+// replaced. Deliveries waiting for a confirmation or a resend are failed with
+// err, and held messages are also reported as MessageError.
+func (c *Client) resetResend(err *WirechatError) {
+	inflight, held := c.resend.reset()
+	for _, m := range inflight {
+		m.delivery.fail(err)
+	}
+	for _, out := range held {
+		out.delivery.fail(err)
+		c.dispatcher.fireError(&MessageError{
+			Payload:  out.in.Data.(MsgPayload),
+			Attempts: out.attempt,
+			Err:      err,
+		})
+	}
+}
+
+// handleInflight inspects an incoming frame for confirmations and rate limits.
+// It returns true if the frame was fully handled and must not be dispatched.
+func (c *Client) handleInflight(ctx context.Context, out Outbound) bool {
+	if out.Type == outboundEvent {
+		switch out.Event {
+		case eventMessage:
+			var ev MessageEvent
+			if err := UnmarshalData(out.Data, &ev); err == nil {
+				if m, ok := c.resend.confirm(ev, c.config().User); ok {
+					m.delivery.confirm(ev.ID)
+				}
+			}
+		case eventHistory:
+			var ev HistoryEvent
+			if err := UnmarshalData(out.Data, &ev); err == nil {
+				c.resend.answer(inboundJoin, ev.Room)
+			}
+		case eventUserLeft:
+			var ev UserEvent
+			if err := UnmarshalData(out.Data, &ev); err == nil && ev.User == c.config().User {
+				c.resend.answer(inboundLeave, ev.Room)
+			}
+		}
+		return false
+	}
+	if out.Type != outboundError || out.Error == nil {
+		return false
+	}
+
+	// Every error answers the oldest in-flight frame
+	m, ok := c.resend.attribute()
+	if !ok {
+		return false
+	}
+	wireErr := FromProtocolError(out.Error)
+	if wireErr.Code != ErrorRateLimited || !c.config().ResendRateLimited {
+		m.delivery.fail(wireErr)
+		return false
+	}
+
+	attempt := m.attempt + 1
+	if attempt > c.config().MaxResendAttempts {
+		m.delivery.fail(wireErr)
+		c.dispatcher.fireError(&MessageError{
+			Payload:  m.payload,
+			Attempts: attempt,
+			Err:      wireErr,
+		})
+		return true
+	}
+
+	c.logger.Warn("message rate limited, scheduling resend", map[string]any{
+		"room":    m.payload.Room,
+		"attempt": attempt,
+	})
+
+	retry := outgoing{in: Inbound{Type: inboundMsg, Data: m.payload}, attempt: attempt, delivery: m.delivery}
+	retry.delivery.setStatus(DeliveryPending)
+	if gen, schedule := c.resend.requeue(m.payload.Room, retry); schedule {
+		go c.flushResend(ctx, m.payload.Room, gen, c.resendDelay(attempt))
+	}
+	return true
+}
+
+// flushResend waits for the backoff delay and moves a room's pending messages
+// to the write queue in order. It stops once a reset supersedes gen.
+func (c *Client) flushResend(ctx context.Context, room string, gen int, delay time.Duration) {
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return
+	}
+
+	for {
+		out, ok := c.resend.head(room, gen)
+		if !ok {
+			return
+		}
+		select {
+		case c.queue.lane(inboundMsg) <- out:
+			c.resend.pop(room)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resendDelay calculates the backoff before a resend attempt.
+func (c *Client) resendDelay(attempt int) time.Duration {
+	delay := c.config().ResendInterval
+	for i := 1; i < attempt && delay < c.config().MaxResendDelay; i++ {
+		delay *= 2
+	}
+	if c.config().MaxResendDelay > 0 && delay > c.config().MaxResendDelay {
+		delay = c.config().MaxResendDelay
+	}
+	return delay
+}
@@ -0,0 +1,103 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResendRateLimited(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResendRateLimited = true
+	cfg.ResendInterval = time.Millisecond
+	c := NewClient(&cfg)
+
+	msg := Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: "hi"}}
+	c.resend.track(outgoing{in: msg})
+
+	rateLimited := Outbound{Type: outboundError, Error: &Error{Code: "rate_limited", Msg: "slow down"}}
+	if !c.handleInflight(context.Background(), rateLimited) {
+		t.Fatalf("expected rate_limited error to be attributed")
+	}
+
+	select {
+	case out := <-c.queue.lane(inboundMsg):
+		if out.attempt != 1 || out.in.Data.(MsgPayload).Text != "hi" {
+			t.Fatalf("unexpected resend: %+v", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message was not resent")
+	}
+}
+
+func TestResendRateLimitedGivesUp(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResendRateLimited = true
+	cfg.MaxResendAttempts = 1
+	c := NewClient(&cfg)
+
+	var errGot error
+	c.OnError(func(err error) { errGot = err })
+
+	c.resend.track(outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: "hi"}}, attempt: 1})
+	c.handleInflight(context.Background(), Outbound{Type: outboundError, Error: &Error{Code: "rate_limited", Msg: "slow down"}})
+
+	var msgErr *MessageError
+	if !errors.As(errGot, &msgErr) {
+		t.Fatalf("expected MessageError, got %v", errGot)
+	}
+	if msgErr.Payload.Text != "hi" || msgErr.Err.Code != ErrorRateLimited {
+		t.Fatalf("unexpected error: %+v", msgErr)
+	}
+}
+
+func TestResendAttribution(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.User = "me"
+	cfg.ResendRateLimited = true
+	cfg.ResendInterval = time.Hour
+	c := NewClient(&cfg)
+	ctx := context.Background()
+	rateLimited := Outbound{Type: outboundError, Error: &Error{Code: "rate_limited", Msg: "slow down"}}
+	echo := func(user string) {
+		raw, _ := json.Marshal(MessageEvent{ID: 7, Room: "general", User: user, Text: "hi"})
+		c.handleInflight(ctx, Outbound{Type: outboundEvent, Event: eventMessage, Data: raw})
+	}
+
+	// An error caused by the join is not blamed on the message behind it
+	d := newDelivery("general", "hi")
+	c.resend.track(outgoing{in: Inbound{Type: inboundJoin, Data: JoinPayload{Room: "general"}}})
+	c.resend.track(outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: "hi"}}, delivery: d})
+	if c.handleInflight(ctx, rateLimited) {
+		t.Fatal("rate_limited for a join was attributed to a message")
+	}
+
+	// Only our own echo confirms the message
+	echo("bob")
+	if d.Status() == DeliveryConfirmed {
+		t.Fatal("another user's message confirmed the delivery")
+	}
+	echo("me")
+	if d.Status() != DeliveryConfirmed || d.ID() != 7 {
+		t.Fatalf("expected confirmation, got %s %d", d.Status(), d.ID())
+	}
+
+	// A reset fails messages held for a resend and releases their room
+	held := newDelivery("general", "again")
+	c.resend.track(outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: "again"}}, delivery: held})
+	if !c.handleInflight(ctx, rateLimited) {
+		t.Fatal("expected rate_limited to schedule a resend")
+	}
+	var errGot error
+	c.OnError(func(err error) { errGot = err })
+	c.resetResend(NewError(ErrorDisconnected, "connection lost"))
+	var msgErr *MessageError
+	if held.Status() != DeliveryFailed || !errors.As(errGot, &msgErr) || msgErr.Payload.Text != "again" {
+		t.Fatalf("held message not failed: %s %v", held.Status(), errGot)
+	}
+	if c.resend.hold(outgoing{in: Inbound{Type: inboundMsg, Data: MsgPayload{Room: "general", Text: "next"}}}) {
+		t.Fatal("room still held after reset")
+	}
+}
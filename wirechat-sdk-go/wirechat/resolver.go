@@ -0,0 +1,230 @@
+package wirechat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+// roomResolver caches the room name ↔ ID mapping from ListRooms. Rooms are
+// learned from every ListRooms, CreateRoom and CreateDirectRoom response; a
+// created room also invalidates the cache, since the server may have changed
+// other rooms (e.g. an existing direct room) the client has not listed.
+type roomResolver struct {
+	c *Client
+
+	refreshMu sync.Mutex // Serializes ListRooms refreshes
+
+	mu      sync.Mutex
+	ids     map[string]int64
+	names   map[int64]string
+	fetched time.Time // Time of the last ListRooms; zero when invalidated
+}
+
+func newRoomResolver(c *Client) *roomResolver {
+	return &roomResolver{
+		c:     c,
+		ids:   make(map[string]int64),
+		names: make(map[int64]string),
+	}
+}
+
+// freshLocked reports whether the last ListRooms is within Config.RoomCacheTTL.
+func (r *roomResolver) freshLocked() bool {
+	return !r.fetched.IsZero() && time.Since(r.fetched) < r.c.config().RoomCacheTTL
+}
+
+// store replaces the cache with a full ListRooms result.
+func (r *roomResolver) store(rooms []rest.RoomInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = make(map[string]int64, len(rooms))
+	r.names = make(map[int64]string, len(rooms))
+	for _, room := range rooms {
+		r.addLocked(room)
+	}
+	r.fetched = time.Now()
+}
+
+// created records a room returned by CreateRoom or CreateDirectRoom and
+// invalidates the cache.
+func (r *roomResolver) created(room rest.RoomInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addLocked(room)
+	r.fetched = time.Time{}
+}
+
+func (r *roomResolver) addLocked(room rest.RoomInfo) {
+	if old, ok := r.names[room.ID]; ok {
+		delete(r.ids, old)
+	}
+	r.ids[room.Name] = room.ID
+	r.names[room.ID] = room.Name
+}
+
+// invalidate makes the next lookup refresh the cache.
+func (r *roomResolver) invalidate() {
+	r.mu.Lock()
+	r.fetched = time.Time{}
+	r.mu.Unlock()
+}
+
+// cachedName returns the cached name of a room without refreshing.
+func (r *roomResolver) cachedName(id int64) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name, ok := r.names[id]
+	return name, ok
+}
+
+// lookup calls find on the cache, refreshing it through ListRooms when it is
+// stale or find misses. A stale entry is still used if the refresh fails.
+func (r *roomResolver) lookup(ctx context.Context, find func() bool) error {
+	r.mu.Lock()
+	fresh := r.freshLocked()
+	found := find()
+	r.mu.Unlock()
+	if found && fresh {
+		return nil
+	}
+
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+	// Another caller may have refreshed while this one waited
+	r.mu.Lock()
+	fresh = r.freshLocked()
+	found = find()
+	r.mu.Unlock()
+	if found && fresh {
+		return nil
+	}
+
+	api := r.c.RESTAPI()
+	if api == nil {
+		return NewError(ErrorInvalidConfig, "REST client not configured")
+	}
+	rooms, err := api.ListRooms(ctx)
+	if err != nil {
+		if found {
+			r.c.logger.Warn("room list refresh failed, using cached entry", map[string]any{"error": err.Error()})
+			return nil
+		}
+		return err
+	}
+	// The REST client reports the result through the observer; store it
+	// again for a rest.API without one.
+	r.store(rooms)
+
+	r.mu.Lock()
+	found = find()
+	r.mu.Unlock()
+	if !found {
+		return NewError(ErrorRoomNotFound, "room not found")
+	}
+	return nil
+}
+
+// RoomID returns the REST ID of a room by name, using the cached ListRooms
+// result while it is younger than Config.RoomCacheTTL.
+func (c *Client) RoomID(ctx context.Context, room string) (int64, error) {
+	var id int64
+	err := c.rooms.lookup(ctx, func() bool {
+		var ok bool
+		id, ok = c.rooms.ids[room]
+		return ok
+	})
+	return id, err
+}
+
+// RoomName returns the name of a room by REST ID, using the cached ListRooms
+// result while it is younger than Config.RoomCacheTTL.
+func (c *Client) RoomName(ctx context.Context, id int64) (string, error) {
+	var name string
+	err := c.rooms.lookup(ctx, func() bool {
+		var ok bool
+		name, ok = c.rooms.names[id]
+		return ok
+	})
+	return name, err
+}
+
+// InvalidateRooms drops the cached room list, so the next lookup calls ListRooms.
+func (c *Client) InvalidateRooms() {
+	c.rooms.invalidate()
+}
+
+// JoinID is Join for a room given by REST ID. The name is resolved with RoomName.
+func (c *Client) JoinID(ctx context.Context, id int64) error {
+	room, err := c.RoomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Join(ctx, room)
+}
+
+// LeaveID is Leave for a room given by REST ID.
+func (c *Client) LeaveID(ctx context.Context, id int64) error {
+	room, err := c.RoomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Leave(ctx, room)
+}
+
+// SendID is Send for a room given by REST ID.
+func (c *Client) SendID(ctx context.Context, id int64, text string) error {
+	room, err := c.RoomName(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, room, text)
+}
+
+// SendWithDeliveryID is SendWithDelivery for a room given by REST ID.
+func (c *Client) SendWithDeliveryID(ctx context.Context, id int64, text string) (*Delivery, error) {
+	room, err := c.RoomName(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendWithDelivery(ctx, room, text)
+}
+
+// resolveRoom fills in whichever of a room name and REST ID is missing: an
+// empty room is looked up by roomID, and roomID 0 by the room name.
+func (c *Client) resolveRoom(ctx context.Context, room string, roomID int64) (string, int64, error) {
+	var err error
+	switch {
+	case room == "" && roomID != 0:
+		room, err = c.RoomName(ctx, roomID)
+	case roomID == 0:
+		roomID, err = c.RoomID(ctx, room)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return room, roomID, nil
+}
+
+// restObserver routes REST responses to the room resolver and the message store feed.
+type restObserver struct {
+	c *Client
+}
+
+var _ rest.Observer = restObserver{}
+
+func (o restObserver) ObserveRooms(rooms []rest.RoomInfo) {
+	o.c.rooms.store(rooms)
+}
+
+func (o restObserver) ObserveRoomCreated(room rest.RoomInfo) {
+	o.c.rooms.created(room)
+}
+
+func (o restObserver) ObserveMessages(roomID int64, msgs []rest.MessageInfo) {
+	if o.c.feed != nil {
+		o.c.feed.observeMessages(roomID, msgs)
+	}
+}
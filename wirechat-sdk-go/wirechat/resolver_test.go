@@ -0,0 +1,75 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+)
+
+func TestRoomResolver(t *testing.T) {
+	rooms := []map[string]any{{"id": 7, "name": "general"}, {"id": 8, "name": "random"}}
+	var lists int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/rooms" && r.Method == http.MethodPost:
+			rooms = append(rooms, map[string]any{"id": 9, "name": "new"})
+			_ = json.NewEncoder(w).Encode(rooms[len(rooms)-1])
+		case r.URL.Path == "/rooms":
+			lists++
+			_ = json.NewEncoder(w).Encode(rooms)
+		case r.URL.Path == "/rooms/7/messages":
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": 2, "user": "bob"}, {"id": 1, "user": "bob"}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	cfg := DefaultConfig()
+	cfg.RESTBaseURL = api.URL
+	cfg.BufferMessages = true
+	c := NewClient(&cfg)
+	ctx := context.Background()
+
+	if id, err := c.RoomID(ctx, "general"); err != nil || id != 7 {
+		t.Fatalf("RoomID = %d (%v)", id, err)
+	}
+	if name, err := c.RoomName(ctx, 8); err != nil || name != "random" || lists != 1 {
+		t.Fatalf("RoomName = %q (%v) after %d lists", name, err, lists)
+	}
+	if _, err := c.RoomID(ctx, "missing"); !errors.Is(err, NewError(ErrorRoomNotFound, "")) || lists != 2 {
+		t.Fatalf("expected room_not_found after a refresh, got %v after %d lists", err, lists)
+	}
+
+	// Creating a room invalidates the cache
+	if _, err := c.REST.CreateRoom(ctx, rest.CreateRoomRequest{Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := c.RoomID(ctx, "new"); err != nil || id != 9 || lists != 3 {
+		t.Fatalf("RoomID(new) = %d (%v) after %d lists", id, err, lists)
+	}
+
+	// ID variants resolve the name; names are never parsed as IDs
+	if err := c.JoinID(ctx, 8); err != nil {
+		t.Fatalf("join by ID: %v", err)
+	}
+	if err := c.Join(ctx, "#8"); err != nil {
+		t.Fatalf("join by name: %v", err)
+	}
+	c.mu.Lock()
+	joined, literal := c.joinedRooms["random"], c.joinedRooms["#8"]
+	c.mu.Unlock()
+	if !joined || !literal {
+		t.Fatalf("joined rooms = %v", c.joinedRooms)
+	}
+	tracker, _ := NewUnreadTracker(c, nil)
+	_ = tracker.MarkRead("general", 1)
+	if n, err := tracker.FetchUnread(ctx, "", 7); err != nil || n != 1 || tracker.Unread("general") != 1 {
+		t.Fatalf("FetchUnread by ID = %d (%v)", n, err)
+	}
+}
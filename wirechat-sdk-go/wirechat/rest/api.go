@@ -0,0 +1,18 @@
+package rest
+
+import "context"
+
+// API is the set of REST operations offered by Client. Depend on it instead
+// of *Client to substitute a fake in tests.
+type API interface {
+	SetToken(token string)
+	Register(ctx context.Context, req RegisterRequest) (*TokenResponse, error)
+	Login(ctx context.Context, req LoginRequest) (*TokenResponse, error)
+	GuestLogin(ctx context.Context) (*TokenResponse, error)
+	CreateRoom(ctx context.Context, req CreateRoomRequest) (*RoomInfo, error)
+	ListRooms(ctx context.Context) ([]RoomInfo, error)
+	CreateDirectRoom(ctx context.Context, req CreateDirectRoomRequest) (*RoomInfo, error)
+	GetMessages(ctx context.Context, roomID int64, limit int, before *int64) (*MessagesResponse, error)
+}
+
+var _ API = (*Client)(nil)
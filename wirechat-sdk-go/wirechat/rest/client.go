@@ -4,17 +4,61 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
 )
 
+// Metrics receives the outcome of every REST request.
+// endpoint is the route template (e.g. "/rooms/{id}/messages"); status is 0 if no response was received.
+type Metrics interface {
+	RESTRequest(endpoint string, status int, duration time.Duration)
+}
+
+// Logger is the logging interface used by the REST client.
+// It has the same method set as wirechat.Logger.
+type Logger interface {
+	Debug(msg string, fields map[string]any)
+	Info(msg string, fields map[string]any)
+	Warn(msg string, fields map[string]any)
+	Error(msg string, fields map[string]any)
+}
+
+// Breaker guards requests with a circuit breaker. Allow returns an error to
+// fail a request fast; Success and Failure report the outcome of allowed requests.
+type Breaker interface {
+	Allow() error
+	Success()
+	Failure()
+}
+
+// Observer receives the rooms and messages returned by successful requests,
+// e.g. to keep a local cache in sync. Calls happen on the requesting goroutine.
+type Observer interface {
+	ObserveRooms(rooms []RoomInfo)    // Full ListRooms result
+	ObserveRoomCreated(room RoomInfo) // CreateRoom or CreateDirectRoom result
+	ObserveMessages(roomID int64, msgs []MessageInfo)
+}
+
+// ErrNoBaseURL is returned by requests made before a base URL is set.
+var ErrNoBaseURL = errors.New("rest: base URL not configured")
+
 // Client provides REST API access to WireChat server.
 type Client struct {
-	baseURL    string
-	token      string
+	mu         sync.RWMutex
+	baseURL    string // Guarded by mu; may change on failover
+	token      string // Guarded by mu; may be rotated while requests run
 	httpClient *http.Client
+	metrics    Metrics
+	tracer     trace.Tracer
+	logger     Logger
+	breaker    Breaker
+	observer   Observer
 }
 
 // NewClient creates a new REST API client.
@@ -25,9 +69,25 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		tracer: trace.Noop{},
 	}
 }
 
+// SetBaseURL switches the API base URL, e.g. when the WebSocket client
+// fails over to another endpoint. It is safe to call concurrently with requests.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.mu.Lock()
+	c.baseURL = baseURL
+	c.mu.Unlock()
+}
+
+// BaseURL returns the current API base URL.
+func (c *Client) BaseURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.baseURL
+}
+
 // SetHTTPClient allows setting a custom HTTP client.
 func (c *Client) SetHTTPClient(client *http.Client) {
 	if client != nil {
@@ -35,9 +95,49 @@ func (c *Client) SetHTTPClient(client *http.Client) {
 	}
 }
 
+// SetMetrics sets the sink for request metrics (optional).
+func (c *Client) SetMetrics(m Metrics) {
+	c.metrics = m
+}
+
+// SetTracer sets the tracer used for request spans (optional).
+// The trace context is propagated to the server via request headers.
+func (c *Client) SetTracer(t trace.Tracer) {
+	if t != nil {
+		c.tracer = t
+	}
+}
+
+// SetLogger sets the logger for request logging (optional).
+func (c *Client) SetLogger(l Logger) {
+	c.logger = l
+}
+
+// SetBreaker sets the circuit breaker consulted before every request (optional).
+// Transport errors and 5xx responses count as failures.
+func (c *Client) SetBreaker(b Breaker) {
+	c.breaker = b
+}
+
+// SetObserver sets the observer notified of rooms and messages returned by
+// successful requests (optional). Set it before issuing requests.
+func (c *Client) SetObserver(o Observer) {
+	c.observer = o
+}
+
 // SetToken sets the JWT token for authenticated requests.
+// It is safe to call concurrently with requests.
 func (c *Client) SetToken(token string) {
+	c.mu.Lock()
 	c.token = token
+	c.mu.Unlock()
+}
+
+// Token returns the current JWT token.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
 }
 
 // Authentication endpoints
@@ -45,7 +145,7 @@ func (c *Client) SetToken(token string) {
 // Register creates a new user account and returns a JWT token.
 func (c *Client) Register(ctx context.Context, req RegisterRequest) (*TokenResponse, error) {
 	var resp TokenResponse
-	if err := c.post(ctx, "/register", req, &resp, false); err != nil {
+	if err := c.post(ctx, "/register", "/register", req, &resp, false); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -54,7 +154,7 @@ func (c *Client) Register(ctx context.Context, req RegisterRequest) (*TokenRespo
 // Login authenticates with existing credentials and returns a JWT token.
 func (c *Client) Login(ctx context.Context, req LoginRequest) (*TokenResponse, error) {
 	var resp TokenResponse
-	if err := c.post(ctx, "/login", req, &resp, false); err != nil {
+	if err := c.post(ctx, "/login", "/login", req, &resp, false); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -63,7 +163,7 @@ func (c *Client) Login(ctx context.Context, req LoginRequest) (*TokenResponse, e
 // GuestLogin creates a temporary guest user and returns a JWT token.
 func (c *Client) GuestLogin(ctx context.Context) (*TokenResponse, error) {
 	var resp TokenResponse
-	if err := c.post(ctx, "/guest", nil, &resp, false); err != nil {
+	if err := c.post(ctx, "/guest", "/guest", nil, &resp, false); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -74,18 +174,24 @@ func (c *Client) GuestLogin(ctx context.Context) (*TokenResponse, error) {
 // CreateRoom creates a new public or private room.
 func (c *Client) CreateRoom(ctx context.Context, req CreateRoomRequest) (*RoomInfo, error) {
 	var resp RoomInfo
-	if err := c.post(ctx, "/rooms", req, &resp, true); err != nil {
+	if err := c.post(ctx, "/rooms", "/rooms", req, &resp, true); err != nil {
 		return nil, err
 	}
+	if c.observer != nil {
+		c.observer.ObserveRoomCreated(resp)
+	}
 	return &resp, nil
 }
 
 // ListRooms returns all accessible rooms for the authenticated user.
 func (c *Client) ListRooms(ctx context.Context) ([]RoomInfo, error) {
 	var resp []RoomInfo
-	if err := c.get(ctx, "/rooms", &resp, true); err != nil {
+	if err := c.get(ctx, "/rooms", "/rooms", &resp, true); err != nil {
 		return nil, err
 	}
+	if c.observer != nil {
+		c.observer.ObserveRooms(resp)
+	}
 	return resp, nil
 }
 
@@ -93,9 +199,12 @@ func (c *Client) ListRooms(ctx context.Context) ([]RoomInfo, error) {
 // This endpoint is idempotent - calling it multiple times with the same peer returns the same room.
 func (c *Client) CreateDirectRoom(ctx context.Context, req CreateDirectRoomRequest) (*RoomInfo, error) {
 	var resp RoomInfo
-	if err := c.post(ctx, "/rooms/direct", req, &resp, true); err != nil {
+	if err := c.post(ctx, "/rooms/direct", "/rooms/direct", req, &resp, true); err != nil {
 		return nil, err
 	}
+	if c.observer != nil {
+		c.observer.ObserveRoomCreated(resp)
+	}
 	return &resp, nil
 }
 
@@ -111,15 +220,18 @@ func (c *Client) GetMessages(ctx context.Context, roomID int64, limit int, befor
 	}
 
 	var resp MessagesResponse
-	if err := c.get(ctx, url, &resp, true); err != nil {
+	if err := c.get(ctx, "/rooms/{id}/messages", url, &resp, true); err != nil {
 		return nil, err
 	}
+	if c.observer != nil {
+		c.observer.ObserveMessages(roomID, resp.Messages)
+	}
 	return &resp, nil
 }
 
 // Helper methods
 
-func (c *Client) post(ctx context.Context, path string, body, dest any, requireAuth bool) error {
+func (c *Client) post(ctx context.Context, endpoint, path string, body, dest any, requireAuth bool) error {
 	var bodyReader io.Reader
 	if body != nil {
 		data, err := json.Marshal(body)
@@ -129,7 +241,11 @@ func (c *Client) post(ctx context.Context, path string, body, dest any, requireA
 		bodyReader = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bodyReader)
+	baseURL := c.BaseURL()
+	if baseURL == "" {
+		return ErrNoBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+path, bodyReader)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -138,32 +254,66 @@ func (c *Client) post(ctx context.Context, path string, body, dest any, requireA
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	if requireAuth && c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if token := c.Token(); requireAuth && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	return c.do(req, dest)
+	return c.do(endpoint, req, dest)
 }
 
-func (c *Client) get(ctx context.Context, path string, dest any, requireAuth bool) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, http.NoBody)
+func (c *Client) get(ctx context.Context, endpoint, path string, dest any, requireAuth bool) error {
+	baseURL := c.BaseURL()
+	if baseURL == "" {
+		return ErrNoBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+path, http.NoBody)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 
-	if requireAuth && c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if token := c.Token(); requireAuth && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	return c.do(req, dest)
+	return c.do(endpoint, req, dest)
 }
 
-func (c *Client) do(req *http.Request, dest any) error {
+func (c *Client) do(endpoint string, req *http.Request, dest any) (err error) {
+	ctx, span := c.tracer.Start(req.Context(), "wirechat.rest "+endpoint,
+		trace.String(trace.AttrEndpoint, endpoint),
+		trace.String(trace.AttrMethod, req.Method),
+	)
+	req = req.WithContext(ctx)
+	c.tracer.Inject(ctx, req.Header)
+
+	start := time.Now()
+	status := 0
+	defer func() {
+		span.SetAttributes(trace.Int(trace.AttrStatusCode, status))
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+		c.observe(endpoint, status, start)
+		c.logRequest(req.Method, endpoint, status, start, err)
+	}()
+
+	if c.breaker != nil {
+		if err := c.breaker.Allow(); err != nil {
+			return err
+		}
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if ctx.Err() == nil {
+			c.reportBreaker(false)
+		}
 		return fmt.Errorf("http request: %w", err)
 	}
 	defer resp.Body.Close()
+	status = resp.StatusCode
+	c.reportBreaker(status < 500)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -188,3 +338,37 @@ func (c *Client) do(req *http.Request, dest any) error {
 
 	return nil
 }
+
+func (c *Client) reportBreaker(ok bool) {
+	switch {
+	case c.breaker == nil:
+	case ok:
+		c.breaker.Success()
+	default:
+		c.breaker.Failure()
+	}
+}
+
+func (c *Client) observe(endpoint string, status int, start time.Time) {
+	if c.metrics != nil {
+		c.metrics.RESTRequest(endpoint, status, time.Since(start))
+	}
+}
+
+func (c *Client) logRequest(method, endpoint string, status int, start time.Time, err error) {
+	if c.logger == nil {
+		return
+	}
+	fields := map[string]any{
+		"method":   method,
+		"endpoint": endpoint,
+		"status":   status,
+		"duration": time.Since(start).String(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		c.logger.Warn("rest request failed", fields)
+		return
+	}
+	c.logger.Debug("rest request", fields)
+}
@@ -0,0 +1,158 @@
+package wirechat
+
+import (
+	"slices"
+	"sync"
+)
+
+// RosterEvent describes a membership change in a joined room. It carries
+// both the diff and a snapshot of the room after the change.
+type RosterEvent struct {
+	Room    string
+	Joined  []string // Users seen for the first time since the last reset
+	Left    []string // Users that left; on reset or Leave, every previous member
+	Members []string // Sorted members after the change
+	Reset   bool     // The roster was cleared because the room was (re)joined
+}
+
+// roster tracks the members of joined rooms from user_joined and user_left
+// events and message authors. A nil roster tracks nothing.
+type roster struct {
+	onChange func(RosterEvent)
+
+	mu    sync.RWMutex
+	rooms map[string]map[string]bool
+}
+
+// newRoster returns nil unless Config.TrackRoster is set.
+func newRoster(cfg *Config, onChange func(RosterEvent)) *roster {
+	if !cfg.TrackRoster {
+		return nil
+	}
+	return &roster{onChange: onChange, rooms: make(map[string]map[string]bool)}
+}
+
+// hooks returns the dispatcher hooks feeding the roster.
+func (r *roster) hooks() eventHooks {
+	return eventHooks{
+		message:    func(ev MessageEvent) { r.add(ev.Room, ev.User) },
+		userJoined: func(ev UserEvent) { r.add(ev.Room, ev.User) },
+		userLeft:   func(ev UserEvent) { r.remove(ev.Room, ev.User) },
+	}
+}
+
+// reset starts tracking room from scratch when it is joined or rejoined
+// after a reconnect, since membership may have changed in the meantime.
+func (r *roster) reset(room string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	old := r.rooms[room]
+	r.rooms[room] = make(map[string]bool)
+	r.mu.Unlock()
+	r.onChange(RosterEvent{Room: room, Left: sortedMembers(old), Members: []string{}, Reset: true})
+}
+
+// drop stops tracking room and reports all of its members as gone.
+func (r *roster) drop(room string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	old, ok := r.rooms[room]
+	delete(r.rooms, room)
+	r.mu.Unlock()
+	if ok && len(old) > 0 {
+		r.onChange(RosterEvent{Room: room, Left: sortedMembers(old), Members: []string{}})
+	}
+}
+
+// add records user as a member of room. Rooms that are not joined are ignored.
+func (r *roster) add(room, user string) {
+	if user == "" {
+		return
+	}
+	r.mu.Lock()
+	members, ok := r.rooms[room]
+	added := ok && !members[user]
+	var snapshot []string
+	if added {
+		members[user] = true
+		snapshot = sortedMembers(members)
+	}
+	r.mu.Unlock()
+	if added {
+		r.onChange(RosterEvent{Room: room, Joined: []string{user}, Members: snapshot})
+	}
+}
+
+// remove records that user left room.
+func (r *roster) remove(room, user string) {
+	r.mu.Lock()
+	members := r.rooms[room]
+	removed := members[user]
+	var snapshot []string
+	if removed {
+		delete(members, user)
+		snapshot = sortedMembers(members)
+	}
+	r.mu.Unlock()
+	if removed {
+		r.onChange(RosterEvent{Room: room, Left: []string{user}, Members: snapshot})
+	}
+}
+
+// members returns the sorted members of room, or nil if it is not tracked.
+func (r *roster) members(room string) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	members, ok := r.rooms[room]
+	if !ok {
+		return nil
+	}
+	return sortedMembers(members)
+}
+
+// snapshot returns the sorted members of every tracked room.
+func (r *roster) snapshot() map[string][]string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rooms := make(map[string][]string, len(r.rooms))
+	for room, members := range r.rooms {
+		rooms[room] = sortedMembers(members)
+	}
+	return rooms
+}
+
+func sortedMembers(set map[string]bool) []string {
+	users := make([]string, 0, len(set))
+	for user := range set {
+		users = append(users, user)
+	}
+	slices.Sort(users)
+	return users
+}
+
+// Members returns the sorted users seen in a joined room since it was last
+// (re)joined. It returns nil if Config.TrackRoster is disabled or the room
+// is not joined. Safe to call from any goroutine.
+func (c *Client) Members(room string) []string {
+	return c.roster.members(room)
+}
+
+// Rosters returns the sorted members of every joined room, or nil if
+// Config.TrackRoster is disabled.
+func (c *Client) Rosters() map[string][]string {
+	return c.roster.snapshot()
+}
+
+// OnRosterChanged registers callback for membership changes in joined rooms.
+// It requires Config.TrackRoster.
+func (c *Client) OnRosterChanged(fn func(RosterEvent)) { c.dispatcher.SetOnRosterChanged(fn) }
@@ -0,0 +1,54 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRoster(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TrackRoster = true
+	c := NewClient(&cfg)
+	c.connected = true
+
+	var events []RosterEvent
+	c.OnRosterChanged(func(ev RosterEvent) { events = append(events, ev) })
+	dispatch := func(event string, v any) {
+		raw, _ := json.Marshal(v)
+		c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: event, Data: raw})
+	}
+
+	if err := c.Join(context.Background(), "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	dispatch(eventUserJoined, UserEvent{Room: "general", User: "bob"})
+	dispatch(eventMessage, MessageEvent{Room: "general", User: "alice", Text: "hi"})
+	dispatch(eventMessage, MessageEvent{Room: "general", User: "bob", Text: "again"})
+	dispatch(eventUserJoined, UserEvent{Room: "random", User: "carol"}) // not joined
+	if got := c.Members("general"); len(got) != 2 || got[0] != "alice" || got[1] != "bob" {
+		t.Fatalf("unexpected members: %v", got)
+	}
+
+	dispatch(eventUserLeft, UserEvent{Room: "general", User: "bob"})
+	last := events[len(events)-1]
+	if len(last.Left) != 1 || last.Left[0] != "bob" || len(last.Members) != 1 {
+		t.Fatalf("unexpected diff: %+v", last)
+	}
+	if c.Members("random") != nil || len(c.Rosters()) != 1 {
+		t.Fatalf("untracked room leaked: %v", c.Rosters())
+	}
+
+	// A rejoin after reconnect starts the room over
+	if err := c.rejoinRooms(context.Background()); err != nil {
+		t.Fatalf("rejoin: %v", err)
+	}
+	last = events[len(events)-1]
+	if !last.Reset || len(last.Left) != 1 || len(c.Members("general")) != 0 {
+		t.Fatalf("expected reset, got %+v", last)
+	}
+	// initial reset, alice, bob joined, bob left, rejoin reset
+	if len(events) != 5 {
+		t.Fatalf("expected 5 roster events, got %d: %+v", len(events), events)
+	}
+}
@@ -45,7 +45,9 @@ func (s ConnectionState) String() string {
 
 // StateEvent represents a state change event.
 type StateEvent struct {
-	OldState ConnectionState
-	NewState ConnectionState
-	Error    error // Optional error that caused the state change
+	OldState  ConnectionState
+	NewState  ConnectionState
+	Transport string // Name of the active or last used transport, e.g. "websocket" or "longpoll"
+	Endpoint  string // URL of the active or last used endpoint
+	Error     error  // Optional error that caused the state change
 }
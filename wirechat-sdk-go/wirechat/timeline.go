@@ -0,0 +1,286 @@
+package wirechat
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// defaultFetchLimit is the REST page size FetchMore uses when limit is not set.
+const defaultFetchLimit = 50
+
+// Gap is a stretch of a timeline with missing messages: everything with an
+// ID strictly between After and Before is unknown.
+type Gap struct {
+	After  int64 // ID of the last message before the gap
+	Before int64 // ID of the first message after the gap
+}
+
+// TimelineEvent is emitted when messages are added to a room timeline.
+type TimelineEvent struct {
+	Room    string
+	Added   []Message // New messages in ID order
+	HasGaps bool      // Whether the timeline still has gaps after the change
+}
+
+// SyncEngine merges join history, live messages and REST history pages into
+// one ordered, de-duplicated timeline per room. It knows which neighbouring
+// messages are contiguous, so it can report gaps left by disconnects and
+// fill them with FetchMore. Guest messages (ID 0) cannot be ordered and are
+// not kept.
+type SyncEngine struct {
+	client *Client
+
+	mu       sync.Mutex
+	rooms    map[string]*timeline
+	onChange func(TimelineEvent)
+}
+
+// timeline is the state of a single room.
+type timeline struct {
+	msgs     []Message      // Sorted by ID
+	linked   map[int64]bool // IDs known to directly follow the previous message
+	complete bool           // The oldest message of the room is present
+	live     bool           // Live messages continue from the newest message
+}
+
+// NewSyncEngine attaches a sync engine to c. Create it before Connect so the
+// join history of every room is seen.
+func NewSyncEngine(c *Client) *SyncEngine {
+	e := &SyncEngine{
+		client: c,
+		rooms:  make(map[string]*timeline),
+	}
+	c.dispatcher.addHooks(eventHooks{
+		message: e.observeMessage,
+		history: e.observeHistory,
+		state:   e.observeState,
+	})
+	return e
+}
+
+// OnChange registers a callback fired whenever messages are added to a timeline.
+func (e *SyncEngine) OnChange(fn func(TimelineEvent)) {
+	e.mu.Lock()
+	e.onChange = fn
+	e.mu.Unlock()
+}
+
+// roomLocked returns the timeline of room, creating it on first use.
+func (e *SyncEngine) roomLocked(room string) *timeline {
+	tl, ok := e.rooms[room]
+	if !ok {
+		tl = &timeline{linked: make(map[int64]bool)}
+		e.rooms[room] = tl
+	}
+	return tl
+}
+
+// observeHistory merges the join history of a room. The server sends the
+// latest messages, so live messages that follow continue from them.
+func (e *SyncEngine) observeHistory(ev HistoryEvent) {
+	block := make([]Message, 0, len(ev.Messages))
+	for _, m := range ev.Messages {
+		block = append(block, MessageFromEvent(m))
+	}
+
+	e.mu.Lock()
+	tl := e.roomLocked(ev.Room)
+	added := tl.merge(block)
+	tl.live = true
+	e.mu.Unlock()
+
+	e.notify(ev.Room, added)
+}
+
+// observeMessage appends a live message. It is contiguous with the newest
+// message only while the room has been followed without interruption.
+func (e *SyncEngine) observeMessage(ev MessageEvent) {
+	m := MessageFromEvent(ev)
+
+	e.mu.Lock()
+	tl := e.roomLocked(ev.Room)
+	block := []Message{m}
+	if n := len(tl.msgs); tl.live && n > 0 && tl.msgs[n-1].ID < m.ID {
+		block = []Message{tl.msgs[n-1], m}
+	}
+	added := tl.merge(block)
+	e.mu.Unlock()
+
+	e.notify(ev.Room, added)
+}
+
+// observeState stops linking live messages once the connection drops:
+// anything sent while disconnected is missing until the next join history.
+func (e *SyncEngine) observeState(ev StateEvent) {
+	if ev.NewState == StateConnected {
+		return
+	}
+	e.mu.Lock()
+	for _, tl := range e.rooms {
+		tl.live = false
+	}
+	e.mu.Unlock()
+}
+
+func (e *SyncEngine) notify(room string, added []Message) {
+	if len(added) == 0 {
+		return
+	}
+	e.mu.Lock()
+	gaps := len(e.rooms[room].gaps()) > 0
+	fn := e.onChange
+	e.mu.Unlock()
+
+	if fn != nil {
+		fn(TimelineEvent{Room: room, Added: added, HasGaps: gaps})
+	}
+}
+
+// merge inserts a block of contiguous messages and returns the new ones.
+// Existing messages are completed with fields only the block carries.
+func (tl *timeline) merge(block []Message) []Message {
+	block = slices.DeleteFunc(slices.Clone(block), func(m Message) bool { return m.ID == 0 })
+	slices.SortFunc(block, func(a, b Message) int { return cmp.Compare(a.ID, b.ID) })
+	block = slices.CompactFunc(block, func(a, b Message) bool { return a.ID == b.ID })
+
+	var added []Message
+	for i, m := range block {
+		pos, found := slices.BinarySearchFunc(tl.msgs, m.ID, func(s Message, id int64) int { return cmp.Compare(s.ID, id) })
+		if found {
+			tl.msgs[pos], _ = mergeMessage(tl.msgs[pos], m)
+		} else {
+			tl.msgs = slices.Insert(tl.msgs, pos, m)
+			added = append(added, m)
+			// A message landing inside a contiguous stretch is part of it.
+			if pos+1 < len(tl.msgs) && tl.linked[tl.msgs[pos+1].ID] {
+				tl.linked[m.ID] = true
+			}
+		}
+		if i > 0 {
+			// Everything between block neighbours is now known: link each
+			// stored message in that range to its predecessor.
+			for j := pos; j > 0 && tl.msgs[j].ID > block[i-1].ID; j-- {
+				tl.linked[tl.msgs[j].ID] = true
+			}
+		}
+	}
+	return added
+}
+
+// gaps lists the interior gaps in ID order.
+func (tl *timeline) gaps() []Gap {
+	if tl == nil {
+		return nil
+	}
+	var gaps []Gap
+	for i := 1; i < len(tl.msgs); i++ {
+		if !tl.linked[tl.msgs[i].ID] {
+			gaps = append(gaps, Gap{After: tl.msgs[i-1].ID, Before: tl.msgs[i].ID})
+		}
+	}
+	return gaps
+}
+
+// Timeline returns the known messages of room in ID order.
+func (e *SyncEngine) Timeline(room string) []Message {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if tl, ok := e.rooms[room]; ok {
+		return slices.Clone(tl.msgs)
+	}
+	return nil
+}
+
+// Gaps returns the gaps between known messages of room, oldest first.
+// Missing history before the oldest message is reported by Complete instead.
+func (e *SyncEngine) Gaps(room string) []Gap {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rooms[room].gaps()
+}
+
+// HasGaps reports whether the timeline of room has missing messages between
+// known ones.
+func (e *SyncEngine) HasGaps(room string) bool {
+	return len(e.Gaps(room)) > 0
+}
+
+// Complete reports whether the timeline of room reaches back to the first
+// message of the room.
+func (e *SyncEngine) Complete(room string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	tl, ok := e.rooms[room]
+	return ok && tl.complete
+}
+
+// FetchMore loads one REST page of history into the timeline of room. The
+// newest gap is filled first; without gaps older history is loaded. roomID
+// is the REST ID of the room: 0 resolves it from the name, and an empty room
+// resolves the name from roomID. limit <= 0 uses 50. It returns the number of
+// messages added, which is 0 once the timeline is complete and gap-free. A
+// gap for which the server returns an empty or final page is closed, since
+// its messages no longer exist, so every call makes progress.
+func (e *SyncEngine) FetchMore(ctx context.Context, room string, roomID int64, limit int) (int, error) {
+	api := e.client.RESTAPI()
+	if api == nil {
+		return 0, NewError(ErrorInvalidConfig, "REST client not configured")
+	}
+	room, roomID, err := e.client.resolveRoom(ctx, room, roomID)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		limit = defaultFetchLimit
+	}
+
+	e.mu.Lock()
+	tl := e.roomLocked(room)
+	gaps := tl.gaps()
+	var anchor, gapStart *Message
+	switch {
+	case len(gaps) > 0:
+		i, _ := slices.BinarySearchFunc(tl.msgs, gaps[len(gaps)-1].Before, func(s Message, id int64) int { return cmp.Compare(s.ID, id) })
+		anchor = &tl.msgs[i]
+		start := tl.msgs[i-1]
+		gapStart = &start
+	case len(tl.msgs) > 0:
+		if tl.complete {
+			e.mu.Unlock()
+			return 0, nil
+		}
+		anchor = &tl.msgs[0]
+	}
+	var before *int64
+	var block []Message
+	if anchor != nil {
+		id := anchor.ID
+		before = &id
+		block = append(block, *anchor)
+	}
+	e.mu.Unlock()
+
+	page, err := api.GetMessages(ctx, roomID, limit, before)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range page.Messages {
+		block = append(block, MessageFromInfo(room, m))
+	}
+	if gapStart != nil && (len(page.Messages) == 0 || !page.HasMore) {
+		// The server has nothing more in the gap, e.g. deleted messages:
+		// close it so callers looping until no gaps are left terminate.
+		block = append(block, *gapStart)
+	}
+	e.mu.Lock()
+	added := tl.merge(block)
+	if gapStart == nil && !page.HasMore {
+		tl.complete = true
+	}
+	e.mu.Unlock()
+
+	e.notify(room, added)
+	return len(added), nil
+}
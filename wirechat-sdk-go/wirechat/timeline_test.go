@@ -0,0 +1,119 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSyncEngine(t *testing.T) {
+	// The room holds messages 1..10; pages are served newest first
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before := int64(11)
+		if v := r.URL.Query().Get("before"); v != "" {
+			_, _ = fmt.Sscan(v, &before)
+		}
+		var limit int
+		_, _ = fmt.Sscan(r.URL.Query().Get("limit"), &limit)
+		var msgs []map[string]any
+		for id := before - 1; id >= 1 && len(msgs) < limit; id-- {
+			msgs = append(msgs, map[string]any{"id": id, "room_id": 7, "user": "bob", "body": fmt.Sprint("m", id)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"messages": msgs, "has_more": len(msgs) > 0 && msgs[len(msgs)-1]["id"].(int64) > 1})
+	}))
+	defer api.Close()
+
+	cfg := DefaultConfig()
+	cfg.RESTBaseURL = api.URL
+	c := NewClient(&cfg)
+	engine := NewSyncEngine(c)
+	var added int
+	engine.OnChange(func(ev TimelineEvent) { added += len(ev.Added) })
+
+	dispatch := func(event string, v any) {
+		raw, _ := json.Marshal(v)
+		c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: event, Data: raw})
+	}
+	dispatch(eventHistory, HistoryEvent{Room: "general", Messages: []MessageEvent{
+		{ID: 7, Room: "general", User: "bob"}, {ID: 6, Room: "general", User: "bob"},
+	}})
+	dispatch(eventMessage, MessageEvent{ID: 8, Room: "general", User: "bob"})
+	// Message 9 is sent while the client is disconnected
+	c.dispatcher.fireStateChange(StateEvent{OldState: StateConnected, NewState: StateReconnecting})
+	dispatch(eventMessage, MessageEvent{ID: 10, Room: "general", User: "bob"})
+	if gaps := engine.Gaps("general"); len(gaps) != 1 || gaps[0] != (Gap{After: 8, Before: 10}) {
+		t.Fatalf("unexpected gaps: %+v", gaps)
+	}
+
+	ids := func() string {
+		var out []string
+		for _, m := range engine.Timeline("general") {
+			out = append(out, fmt.Sprint(m.ID))
+		}
+		return strings.Join(out, ",")
+	}
+	ctx := context.Background()
+	if n, err := engine.FetchMore(ctx, "general", 7, 5); err != nil || n != 2 {
+		t.Fatalf("fill gap: added %d (%v)", n, err)
+	}
+	if engine.HasGaps("general") || engine.Complete("general") || ids() != "5,6,7,8,9,10" {
+		t.Fatalf("after gap fill: %s, gaps=%v", ids(), engine.Gaps("general"))
+	}
+	if n, err := engine.FetchMore(ctx, "general", 7, 5); err != nil || n != 4 || !engine.Complete("general") {
+		t.Fatalf("backfill: added %d (%v)", n, err)
+	}
+	if n, _ := engine.FetchMore(ctx, "general", 7, 5); n != 0 || added != 10 {
+		t.Fatalf("expected a complete timeline, added %d, %d total", n, added)
+	}
+	if got := engine.Timeline("general")[8]; got.RoomID != 7 || got.Text != "m9" || got.Event().Text != "m9" {
+		t.Fatalf("unexpected merged message: %+v", got)
+	}
+}
+
+func TestSyncEngineEmptyGap(t *testing.T) {
+	// Messages 9, 11 and 13 were deleted: the page for the newest gap is
+	// empty, the page for the older one is the last
+	pages := []string{`{"messages":[],"has_more":true}`, `{"messages":[{"id":10,"room_id":7}],"has_more":false}`}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := pages[0]
+		pages = pages[1:]
+		_, _ = w.Write([]byte(page))
+	}))
+	defer api.Close()
+
+	cfg := DefaultConfig()
+	cfg.RESTBaseURL = api.URL
+	c := NewClient(&cfg)
+	engine := NewSyncEngine(c)
+
+	dispatch := func(event string, v any) {
+		raw, _ := json.Marshal(v)
+		c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: event, Data: raw})
+	}
+	dispatch(eventHistory, HistoryEvent{Room: "general", Messages: []MessageEvent{{ID: 7, Room: "general", User: "bob"}}})
+	dispatch(eventMessage, MessageEvent{ID: 8, Room: "general", User: "bob"})
+	c.dispatcher.fireStateChange(StateEvent{OldState: StateConnected, NewState: StateReconnecting})
+	dispatch(eventMessage, MessageEvent{ID: 12, Room: "general", User: "bob"})
+	dispatch(eventMessage, MessageEvent{ID: 14, Room: "general", User: "bob"})
+	if gaps := engine.Gaps("general"); len(gaps) != 2 {
+		t.Fatalf("unexpected gaps: %+v", gaps)
+	}
+
+	ctx := context.Background()
+	if n, err := engine.FetchMore(ctx, "general", 7, 5); err != nil || n != 0 {
+		t.Fatalf("empty page: added %d (%v)", n, err)
+	}
+	if gaps := engine.Gaps("general"); len(gaps) != 1 || gaps[0] != (Gap{After: 8, Before: 12}) {
+		t.Fatalf("expected the newest gap to be closed, got %+v", gaps)
+	}
+	if n, err := engine.FetchMore(ctx, "general", 7, 5); err != nil || n != 1 {
+		t.Fatalf("last page: added %d (%v)", n, err)
+	}
+	if engine.HasGaps("general") || engine.Complete("general") {
+		t.Fatalf("expected no gaps and no complete history, got %+v", engine.Gaps("general"))
+	}
+}
@@ -0,0 +1,198 @@
+package wirechat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errUnsupportedTOML reports valid TOML that parseTOML does not implement.
+var errUnsupportedTOML = errors.New("unsupported TOML construct")
+
+// parseTOML parses the subset of TOML used by config files: key/value pairs
+// with strings, integers, booleans and single-line arrays, [tables] and
+// [[arrays of tables]]. Dotted keys and table names, multi-line strings and
+// arrays, nested arrays, inline tables, floats and dates are rejected with
+// errUnsupportedTOML.
+func parseTOML(data []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	current := root
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripTOMLComment(sc.Text()))
+		if line == "" {
+			continue
+		}
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, args...))
+		}
+		unsupported := func(what string) error {
+			return fmt.Errorf("line %d: %w: %s", lineNo, errUnsupportedTOML, what)
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			name, ok := strings.CutSuffix(line[2:], "]]")
+			if !ok {
+				return nil, fail("unterminated array of tables")
+			}
+			name = strings.TrimSpace(name)
+			if !bareTOMLKey(name) {
+				return nil, unsupported(fmt.Sprintf("array of tables name %q", name))
+			}
+			list, _ := root[name].([]any)
+			if _, exists := root[name]; exists && list == nil {
+				return nil, fail("%q is not an array of tables", name)
+			}
+			current = make(map[string]any)
+			root[name] = append(list, current)
+		case strings.HasPrefix(line, "["):
+			name, ok := strings.CutSuffix(line[1:], "]")
+			if !ok {
+				return nil, fail("unterminated table header")
+			}
+			name = strings.TrimSpace(name)
+			if !bareTOMLKey(name) {
+				return nil, unsupported(fmt.Sprintf("table name %q", name))
+			}
+			if _, exists := root[name]; exists {
+				return nil, fail("duplicate table %q", name)
+			}
+			current = make(map[string]any)
+			root[name] = current
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fail("expected key = value")
+			}
+			key = strings.TrimSpace(key)
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			} else if !bareTOMLKey(key) {
+				return nil, unsupported(fmt.Sprintf("key %q", key))
+			}
+			if key == "" {
+				return nil, fail("empty key")
+			}
+			if _, exists := current[key]; exists {
+				return nil, fail("duplicate key %q", key)
+			}
+			v, err := parseTOMLValue(strings.TrimSpace(value))
+			if errors.Is(err, errUnsupportedTOML) {
+				return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+			}
+			if err != nil {
+				return nil, fail("%s: %v", key, err)
+			}
+			current[key] = v
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// bareTOMLKey reports whether s is a non-dotted bare key.
+func bareTOMLKey(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+	}) < 0
+}
+
+func parseTOMLValue(s string) (any, error) {
+	unsupported := func(what string) error { return fmt.Errorf("%w: %s", errUnsupportedTOML, what) }
+	switch {
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		return nil, unsupported("multi-line string")
+	case strings.HasPrefix(s, "{"):
+		return nil, unsupported("inline table")
+	case s == "":
+		return nil, fmt.Errorf("missing value")
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated literal string")
+		}
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		inner, ok := strings.CutSuffix(s[1:], "]")
+		if !ok {
+			return nil, unsupported("multi-line array")
+		}
+		items := []any{}
+		for _, part := range splitTOMLArray(inner) {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			if strings.HasPrefix(part, "[") {
+				return nil, unsupported("nested array")
+			}
+			v, err := parseTOMLValue(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 0, 64)
+	if err == nil {
+		return n, nil
+	}
+	if _, ferr := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); ferr == nil || strings.HasSuffix(s, "inf") || strings.HasSuffix(s, "nan") {
+		return nil, unsupported("float " + s)
+	}
+	if len(s) >= 8 && strings.ContainsAny(s[:8], "-:") && s[0] >= '0' && s[0] <= '9' {
+		return nil, unsupported("date-time " + s)
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
+
+// splitTOMLArray splits array items on commas outside of strings.
+func splitTOMLArray(s string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// stripTOMLComment removes a trailing # comment outside of strings.
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,65 @@
+// Package trace defines the minimal tracing interface used by the SDK.
+// It has no dependencies so the core module stays lightweight; adapters for
+// tracing libraries (e.g. the otel submodule) implement Tracer.
+package trace
+
+import (
+	"context"
+	"net/http"
+)
+
+// Attribute keys set on SDK spans.
+const (
+	AttrRoom       = "wirechat.room"
+	AttrFrameType  = "wirechat.frame_type"
+	AttrAttempt    = "wirechat.reconnect.attempt"
+	AttrErrorCode  = "wirechat.error_code"
+	AttrEndpoint   = "http.route"
+	AttrMethod     = "http.request.method"
+	AttrStatusCode = "http.response.status_code"
+)
+
+// Attribute is a key/value pair attached to a span.
+// Value is one of string, int, int64, bool or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String creates a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int creates an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: value} }
+
+// Tracer starts spans and propagates trace context.
+type Tracer interface {
+	// Start creates a span as a child of any span in ctx.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+	// Inject writes the trace context of ctx into outgoing HTTP headers.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Noop is a Tracer that records nothing.
+type Noop struct{}
+
+// Start returns ctx unchanged and a span that records nothing.
+func (Noop) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+// Inject does nothing.
+func (Noop) Inject(context.Context, http.Header) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
@@ -0,0 +1,24 @@
+package wirechat
+
+import (
+	"context"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/trace"
+)
+
+// startFrameSpan starts a span for a room command.
+func (c *Client) startFrameSpan(ctx context.Context, name, frameType, room string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, name,
+		trace.String(trace.AttrRoom, room),
+		trace.String(trace.AttrFrameType, frameType),
+	)
+}
+
+// endSpan records err with its error code on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetAttributes(trace.String(trace.AttrErrorCode, errorCode(err).String()))
+		span.RecordError(err)
+	}
+	span.End()
+}
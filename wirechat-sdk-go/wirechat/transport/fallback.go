@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Fallback tries transports in order and uses the first one that connects,
+// e.g. Fallback{WebSocket{}, LongPoll{}} downgrades to long-polling when a
+// proxy strips WebSocket upgrades.
+type Fallback []Transport
+
+// Name lists the wrapped transports, e.g. "websocket,longpoll".
+func (f Fallback) Name() string {
+	names := make([]string, len(f))
+	for i, t := range f {
+		names[i] = t.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Dial returns the first successful connection. If every transport fails the
+// errors are joined.
+func (f Fallback) Dial(ctx context.Context, url string, subprotocols []string) (FrameConn, error) {
+	var errs []error
+	for _, t := range f {
+		conn, err := t.Dial(ctx, url, subprotocols)
+		if err == nil {
+			return namedConn{FrameConn: conn, name: ConnName(conn, t)}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", t.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("transport: no transports configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ConnName returns the name of the transport that established conn.
+// For connections dialed through Fallback this is the transport that succeeded.
+func ConnName(conn FrameConn, t Transport) string {
+	if n, ok := conn.(namedConn); ok {
+		return n.name
+	}
+	return t.Name()
+}
+
+// namedConn remembers which transport of a Fallback established the connection.
+type namedConn struct {
+	FrameConn
+	name string
+}
@@ -0,0 +1,377 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Long-polling session states.
+const (
+	pollOpen int32 = iota
+	pollClosed
+	pollReset
+)
+
+// Content types distinguishing text and binary frames sent by long-polling clients.
+const (
+	contentTypeText   = "text/plain; charset=utf-8"
+	contentTypeBinary = "application/octet-stream"
+)
+
+// pollCloseTimeout bounds how long Close waits for the server to end the session.
+const pollCloseTimeout = 5 * time.Second
+
+// Polls that fail on the way are retried this many times, pollRetryDelay
+// apart, before the connection is reported lost. The server sends the
+// unacknowledged batch again.
+const (
+	pollRetries    = 3
+	pollRetryDelay = 500 * time.Millisecond
+)
+
+// maxPollResponse bounds a poll response: LongPollServer batches frames up
+// to pollBatchSize, and only a single frame may be larger.
+const maxPollResponse = 5 + maxSocketFrame
+
+// errPollTooLarge reports a response over maxPollResponse. It is not retried,
+// since the server would send the same batch again.
+var errPollTooLarge = fmt.Errorf("transport: poll response exceeds %d bytes", maxPollResponse)
+
+// pollSeqHeader carries the sequence number of a poll batch. The client
+// acknowledges it with the ack query parameter of the next poll.
+const pollSeqHeader = "X-Wirechat-Poll-Seq"
+
+// LongPoll carries frames over plain HTTP requests for networks where proxies
+// strip WebSocket upgrades. ws:// and wss:// URLs are mapped to http:// and
+// https://. The server side is LongPollServer.
+//
+// A session is opened with POST, frames are sent with POST and received with
+// GET requests held open until frames arrive, HEAD checks liveness and DELETE
+// closes the session. Each GET acknowledges the last batch received, and a
+// failed GET is retried, so frames of a lost response are delivered again.
+type LongPoll struct {
+	HTTPClient *http.Client // Optional client (default: http.DefaultClient)
+	Header     http.Header  // Optional extra request headers
+	Path       string       // Optional path replacing the dial URL's path
+}
+
+// Name returns "longpoll".
+func (LongPoll) Name() string { return "longpoll" }
+
+// longPollSession is the response to a session open request.
+type longPollSession struct {
+	Session     string `json:"session"`
+	Subprotocol string `json:"subprotocol,omitempty"`
+}
+
+// Dial opens a long-polling session.
+func (t LongPoll) Dial(ctx context.Context, rawURL string, subprotocols []string) (FrameConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("transport: unsupported long-polling scheme %q", u.Scheme)
+	}
+	if t.Path != "" {
+		u.Path = t.Path
+	}
+
+	pollCtx, cancel := context.WithCancel(context.Background())
+	c := &pollConn{
+		client: t.HTTPClient,
+		header: t.Header,
+		base:   *u,
+		ctx:    pollCtx,
+		cancel: cancel,
+	}
+	if c.client == nil {
+		c.client = http.DefaultClient
+	}
+
+	q := u.Query()
+	q.Set("subprotocols", strings.Join(subprotocols, ","))
+	u.RawQuery = q.Encode()
+
+	status, _, body, err := c.do(ctx, http.MethodPost, u.String(), "", nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if status != http.StatusOK {
+		cancel()
+		return nil, fmt.Errorf("transport: open session: unexpected status %d", status)
+	}
+	var sess longPollSession
+	if err := json.Unmarshal(body, &sess); err != nil || sess.Session == "" {
+		cancel()
+		return nil, fmt.Errorf("transport: open session: invalid response: %s", body)
+	}
+	c.session, c.subprotocol = sess.Session, sess.Subprotocol
+	return c, nil
+}
+
+type pollConn struct {
+	client      *http.Client
+	header      http.Header
+	base        url.URL
+	session     string
+	subprotocol string
+
+	ctx    context.Context // Cancelled on close to abort pending requests
+	cancel context.CancelFunc
+	state  atomic.Int32
+
+	mu      sync.Mutex
+	pending []pollFrame // Frames received in the last poll and not yet read
+	ack     uint64      // Sequence number of the last batch received
+}
+
+type pollFrame struct {
+	typ  MessageType
+	data []byte
+}
+
+func (c *pollConn) ReadFrame(ctx context.Context) (MessageType, []byte, error) {
+	for {
+		c.mu.Lock()
+		if len(c.pending) > 0 {
+			f := c.pending[0]
+			c.pending = c.pending[1:]
+			c.mu.Unlock()
+			return f.typ, f.data, nil
+		}
+		c.mu.Unlock()
+
+		status, header, body, err := c.poll(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch status {
+		case http.StatusOK:
+			seq, err := strconv.ParseUint(header.Get(pollSeqHeader), 10, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("transport: poll: invalid sequence %q", header.Get(pollSeqHeader))
+			}
+			frames, err := parsePollFrames(body)
+			if err != nil {
+				return 0, nil, err
+			}
+			c.mu.Lock()
+			if seq > c.ack {
+				c.ack = seq
+				c.pending = append(c.pending, frames...)
+			}
+			c.mu.Unlock()
+		case http.StatusNoContent:
+			// Poll timed out without frames
+		case http.StatusGone:
+			c.state.CompareAndSwap(pollOpen, pollClosed)
+			return 0, nil, ErrClosed
+		default:
+			return 0, nil, fmt.Errorf("transport: poll: unexpected status %d", status)
+		}
+	}
+}
+
+// poll sends a GET acknowledging the last batch received. Network errors
+// and gateway failures are retried while the connection is open.
+func (c *pollConn) poll(ctx context.Context) (int, http.Header, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.stateErr(); err != nil {
+			return 0, nil, nil, err
+		}
+		c.mu.Lock()
+		target := c.endpoint() + "&ack=" + strconv.FormatUint(c.ack, 10)
+		c.mu.Unlock()
+
+		status, header, body, err := c.do(ctx, http.MethodGet, target, "", nil)
+		if err == nil && status < http.StatusInternalServerError {
+			return status, header, body, nil
+		}
+		if errors.Is(err, errPollTooLarge) {
+			return 0, nil, nil, err
+		}
+		if stateErr := c.stateErr(); stateErr != nil {
+			return 0, nil, nil, stateErr
+		}
+		if err == nil {
+			err = fmt.Errorf("transport: poll: unexpected status %d", status)
+		}
+		if attempt == pollRetries || ctx.Err() != nil {
+			return 0, nil, nil, err
+		}
+		select {
+		case <-time.After(pollRetryDelay):
+		case <-ctx.Done():
+			return 0, nil, nil, err
+		case <-c.ctx.Done():
+			return 0, nil, nil, c.stateErr()
+		}
+	}
+}
+
+func (c *pollConn) WriteFrame(ctx context.Context, typ MessageType, data []byte) error {
+	if err := c.stateErr(); err != nil {
+		return err
+	}
+	contentType := contentTypeText
+	if typ == MessageBinary {
+		contentType = contentTypeBinary
+	}
+	status, _, _, err := c.do(ctx, http.MethodPost, c.endpoint(), contentType, data)
+	if err != nil {
+		return err
+	}
+	return c.statusErr("send", status)
+}
+
+func (c *pollConn) Ping(ctx context.Context) error {
+	if err := c.stateErr(); err != nil {
+		return err
+	}
+	status, _, _, err := c.do(ctx, http.MethodHead, c.endpoint(), "", nil)
+	if err != nil {
+		return err
+	}
+	return c.statusErr("ping", status)
+}
+
+func (c *pollConn) Subprotocol() string { return c.subprotocol }
+
+func (c *pollConn) Close() error {
+	if !c.state.CompareAndSwap(pollOpen, pollClosed) {
+		return nil
+	}
+	defer c.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pollCloseTimeout)
+	defer cancel()
+	status, _, _, err := c.do(ctx, http.MethodDelete, c.endpoint(), "", nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent && status != http.StatusGone {
+		return fmt.Errorf("transport: close: unexpected status %d", status)
+	}
+	return nil
+}
+
+func (c *pollConn) CloseNow() error {
+	c.state.CompareAndSwap(pollOpen, pollReset)
+	c.cancel()
+	return nil
+}
+
+func (c *pollConn) stateErr() error {
+	switch c.state.Load() {
+	case pollClosed:
+		return ErrClosed
+	case pollReset:
+		return ErrReset
+	}
+	return nil
+}
+
+func (c *pollConn) statusErr(op string, status int) error {
+	switch status {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusGone:
+		return ErrClosed
+	}
+	return fmt.Errorf("transport: %s: unexpected status %d", op, status)
+}
+
+func (c *pollConn) endpoint() string {
+	u := c.base
+	q := u.Query()
+	q.Set("session", c.session)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// do performs a request that is aborted by ctx or by closing the connection,
+// returning the status code, headers and full body.
+func (c *pollConn) do(ctx context.Context, method, target, contentType string, body []byte) (int, http.Header, []byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if c.ctx != nil {
+		stop := context.AfterFunc(c.ctx, cancel)
+		defer stop()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPollResponse+1))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(data) > maxPollResponse {
+		return 0, nil, nil, errPollTooLarge
+	}
+	return resp.StatusCode, resp.Header, data, nil
+}
+
+// parsePollFrames splits a poll response into length-prefixed frames.
+func parsePollFrames(body []byte) ([]pollFrame, error) {
+	var frames []pollFrame
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, errors.New("transport: truncated poll frame")
+		}
+		kind, n := body[0], binary.BigEndian.Uint32(body[1:5])
+		if uint64(n) > uint64(len(body)-5) {
+			return nil, errors.New("transport: truncated poll frame")
+		}
+		typ := MessageText
+		switch kind {
+		case kindText:
+		case kindBinary:
+			typ = MessageBinary
+		default:
+			return nil, fmt.Errorf("transport: unexpected frame kind %d", kind)
+		}
+		frames = append(frames, pollFrame{typ: typ, data: body[5 : 5+n]})
+		body = body[5+n:]
+	}
+	return frames, nil
+}
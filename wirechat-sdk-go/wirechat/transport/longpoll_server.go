@@ -0,0 +1,273 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Long-polling server defaults.
+const (
+	defaultPollTimeout    = 25 * time.Second
+	defaultSessionTimeout = 60 * time.Second
+	pollBatchSize         = 1 << 20 // Batches stay within this size unless a single frame is larger
+)
+
+// LongPollServer is the server side of the LongPoll transport. Mount it as an
+// http.Handler and call Accept to receive a FrameConn for each new session,
+// the same way as with Memory.
+type LongPollServer struct {
+	PollTimeout    time.Duration // How long a poll waits for frames (default: 25s)
+	SessionTimeout time.Duration // Idle time before a session is dropped (default: 60s)
+
+	subprotocols []string
+	accept       chan FrameConn
+
+	mu       sync.Mutex
+	sessions map[string]*pollSession
+}
+
+// NewLongPollServer creates a handler supporting the given subprotocols in
+// addition to none.
+func NewLongPollServer(subprotocols ...string) *LongPollServer {
+	return &LongPollServer{
+		PollTimeout:    defaultPollTimeout,
+		SessionTimeout: defaultSessionTimeout,
+		subprotocols:   subprotocols,
+		accept:         make(chan FrameConn),
+		sessions:       make(map[string]*pollSession),
+	}
+}
+
+// Accept returns the server end of the next opened session.
+func (s *LongPollServer) Accept(ctx context.Context) (FrameConn, error) {
+	select {
+	case conn := <-s.accept:
+		return conn, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pollSession is the handler's end of a session pipe.
+type pollSession struct {
+	conn   FrameConn
+	timer  *time.Timer
+	pollMu sync.Mutex // Serializes polls so frames are delivered in order
+	seq    uint64     // Sequence number of the last batch sent
+	batch  []byte     // Last batch, kept until the client acknowledges it
+	next   *pollFrame // Frame that did not fit into the last batch
+}
+
+func (s *LongPollServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("session")
+	if id == "" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "session required", http.StatusBadRequest)
+			return
+		}
+		s.open(w, r)
+		return
+	}
+
+	sess := s.lookup(id)
+	if sess == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.send(w, r, id, sess)
+	case http.MethodGet:
+		s.poll(w, r, id, sess)
+	case http.MethodHead:
+		if err := sess.conn.Ping(r.Context()); err != nil {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.remove(id)
+		_ = sess.conn.Close()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LongPollServer) open(w http.ResponseWriter, r *http.Request) {
+	var offered []string
+	if p := r.URL.Query().Get("subprotocols"); p != "" {
+		offered = strings.Split(p, ",")
+	}
+	subprotocol := negotiate(offered, s.subprotocols)
+	handlerEnd, serverEnd := Pipe(subprotocol)
+
+	select {
+	case s.accept <- serverEnd:
+	case <-r.Context().Done():
+		return
+	}
+
+	id := newSessionID()
+	sess := &pollSession{conn: handlerEnd}
+	sess.timer = time.AfterFunc(s.SessionTimeout, func() {
+		// The client stopped polling; report a lost connection to the server
+		s.remove(id)
+		_ = handlerEnd.CloseNow()
+	})
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(longPollSession{Session: id, Subprotocol: subprotocol})
+}
+
+func (s *LongPollServer) send(w http.ResponseWriter, r *http.Request, id string, sess *pollSession) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxSocketFrame+1))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxSocketFrame {
+		http.Error(w, "frame too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	typ := MessageText
+	if r.Header.Get("Content-Type") == contentTypeBinary {
+		typ = MessageBinary
+	}
+
+	if err := sess.conn.WriteFrame(r.Context(), typ, data); err != nil {
+		s.remove(id)
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	s.touch(id, sess)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// poll answers with the next batch of frames. Every batch carries a sequence
+// number and the client acknowledges the last batch it received in the next
+// poll; until then the batch is sent again, so a response lost on the way
+// does not lose its frames.
+func (s *LongPollServer) poll(w http.ResponseWriter, r *http.Request, id string, sess *pollSession) {
+	sess.pollMu.Lock()
+	defer sess.pollMu.Unlock()
+	defer s.touch(id, sess)
+
+	if sess.batch != nil {
+		ack, _ := strconv.ParseUint(r.URL.Query().Get("ack"), 10, 64)
+		if ack < sess.seq {
+			writeBatch(w, sess.seq, sess.batch)
+			return
+		}
+		sess.batch = nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.PollTimeout)
+	defer cancel()
+
+	var typ MessageType
+	var data []byte
+	var err error
+	if sess.next != nil {
+		typ, data = sess.next.typ, sess.next.data
+		sess.next = nil
+	} else {
+		typ, data, err = sess.conn.ReadFrame(ctx)
+	}
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case errors.Is(err, ErrClosed):
+		s.remove(id)
+		w.WriteHeader(http.StatusGone)
+		return
+	default:
+		s.remove(id)
+		http.Error(w, "session lost", http.StatusNotFound)
+		return
+	}
+
+	if len(data) > maxSocketFrame {
+		// The client would reject the response
+		s.remove(id)
+		http.Error(w, "frame too large", http.StatusInternalServerError)
+		return
+	}
+
+	// Batch frames that are already buffered. A frame that would push the
+	// batch past pollBatchSize waits for the next poll.
+	body := appendFrame(nil, frameKind(typ), data)
+	done, stop := context.WithCancel(context.Background())
+	stop()
+	for {
+		typ, data, err := sess.conn.ReadFrame(done)
+		if err != nil {
+			break
+		}
+		if len(body)+5+len(data) > pollBatchSize {
+			sess.next = &pollFrame{typ: typ, data: data}
+			break
+		}
+		body = appendFrame(body, frameKind(typ), data)
+	}
+
+	sess.seq++
+	sess.batch = body
+	writeBatch(w, sess.seq, body)
+}
+
+func writeBatch(w http.ResponseWriter, seq uint64, body []byte) {
+	w.Header().Set("Content-Type", contentTypeBinary)
+	w.Header().Set(pollSeqHeader, strconv.FormatUint(seq, 10))
+	_, _ = w.Write(body)
+}
+
+func (s *LongPollServer) lookup(id string) *pollSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+func (s *LongPollServer) touch(id string, sess *pollSession) {
+	if s.lookup(id) == sess {
+		sess.timer.Reset(s.SessionTimeout)
+	}
+}
+
+func (s *LongPollServer) remove(id string) {
+	s.mu.Lock()
+	sess := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if sess != nil {
+		sess.timer.Stop()
+	}
+}
+
+func frameKind(typ MessageType) byte {
+	if typ == MessageBinary {
+		return kindBinary
+	}
+	return kindText
+}
+
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,139 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrReset is returned by reads on an in-memory connection dropped with CloseNow.
+var ErrReset = errors.New("transport: connection reset")
+
+// memoryBuffer is the number of frames an in-memory connection buffers per direction.
+const memoryBuffer = 64
+
+// Memory is an in-process transport. Each Dial is paired with a call to
+// Accept, which returns the server end of the connection. The dial URL is ignored.
+type Memory struct {
+	subprotocols []string
+	accept       chan FrameConn
+}
+
+// NewMemory creates an in-memory transport whose server supports the given
+// subprotocols in addition to none.
+func NewMemory(subprotocols ...string) *Memory {
+	return &Memory{subprotocols: subprotocols, accept: make(chan FrameConn)}
+}
+
+// Name returns "memory".
+func (*Memory) Name() string { return "memory" }
+
+// Dial blocks until the server end is accepted or ctx is done.
+func (m *Memory) Dial(ctx context.Context, _ string, subprotocols []string) (FrameConn, error) {
+	client, server := Pipe(negotiate(subprotocols, m.subprotocols))
+	select {
+	case m.accept <- server:
+		return client, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Accept returns the server end of the next dialed connection.
+func (m *Memory) Accept(ctx context.Context) (FrameConn, error) {
+	select {
+	case conn := <-m.accept:
+		return conn, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Pipe creates a connected pair of in-memory frame connections.
+func Pipe(subprotocol string) (FrameConn, FrameConn) {
+	st := &pipeState{done: make(chan struct{})}
+	a := make(chan pipeFrame, memoryBuffer)
+	b := make(chan pipeFrame, memoryBuffer)
+	return &pipeConn{state: st, in: a, out: b, subprotocol: subprotocol},
+		&pipeConn{state: st, in: b, out: a, subprotocol: subprotocol}
+}
+
+type pipeFrame struct {
+	typ  MessageType
+	data []byte
+}
+
+// pipeState is shared by both ends of a pipe.
+type pipeState struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func (s *pipeState) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+type pipeConn struct {
+	state       *pipeState
+	in          <-chan pipeFrame
+	out         chan<- pipeFrame
+	subprotocol string
+}
+
+func (c *pipeConn) ReadFrame(ctx context.Context) (MessageType, []byte, error) {
+	// Deliver frames written before a graceful close
+	select {
+	case f := <-c.in:
+		return f.typ, f.data, nil
+	default:
+	}
+	select {
+	case f := <-c.in:
+		return f.typ, f.data, nil
+	case <-c.state.done:
+		return 0, nil, c.state.err
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+func (c *pipeConn) WriteFrame(ctx context.Context, typ MessageType, data []byte) error {
+	select {
+	case <-c.state.done:
+		return c.state.err
+	default:
+	}
+	select {
+	case c.out <- pipeFrame{typ: typ, data: append([]byte(nil), data...)}:
+		return nil
+	case <-c.state.done:
+		return c.state.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *pipeConn) Ping(ctx context.Context) error {
+	select {
+	case <-c.state.done:
+		return c.state.err
+	default:
+		return ctx.Err()
+	}
+}
+
+func (c *pipeConn) Subprotocol() string { return c.subprotocol }
+
+func (c *pipeConn) Close() error {
+	c.state.close(ErrClosed)
+	return nil
+}
+
+func (c *pipeConn) CloseNow() error {
+	c.state.close(ErrReset)
+	return nil
+}
@@ -0,0 +1,263 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSocketFrame bounds the payload size accepted by Socket connections.
+const maxSocketFrame = 16 << 20
+
+// socketCloseTimeout bounds how long Close waits to send the close frame.
+const socketCloseTimeout = 5 * time.Second
+
+// Socket frame kinds. Every frame is a kind byte followed by a big-endian
+// uint32 payload length and the payload.
+const (
+	kindText byte = iota + 1
+	kindBinary
+	kindPing
+	kindPong
+	kindClose
+	kindHandshake
+)
+
+// aLongTimeAgo is used to interrupt blocked I/O when a context is done.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Socket dials length-prefixed frame connections over TCP ("tcp://host:port")
+// or Unix sockets ("unix:///path/to/socket"). The server side is AcceptSocket.
+type Socket struct {
+	Dialer net.Dialer
+}
+
+// Name returns "socket".
+func (Socket) Name() string { return "socket" }
+
+// Dial connects and performs the subprotocol handshake.
+func (t Socket) Dial(ctx context.Context, rawURL string, subprotocols []string) (FrameConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var network, address string
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		network, address = u.Scheme, u.Host
+	case "unix":
+		network, address = u.Scheme, u.Path
+	default:
+		return nil, fmt.Errorf("transport: unsupported socket scheme %q", u.Scheme)
+	}
+
+	nc, err := t.Dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	c := newSocketConn(nc)
+
+	if err := c.write(ctx, kindHandshake, []byte(strings.Join(subprotocols, ","))); err != nil {
+		_ = nc.Close()
+		return nil, fmt.Errorf("transport: send handshake: %w", err)
+	}
+	kind, payload, err := c.read(ctx)
+	if err == nil && kind != kindHandshake {
+		err = fmt.Errorf("unexpected frame kind %d", kind)
+	}
+	if err != nil {
+		_ = nc.Close()
+		return nil, fmt.Errorf("transport: read handshake: %w", err)
+	}
+	c.subprotocol = string(payload)
+	return c, nil
+}
+
+// AcceptSocket performs the server side of the handshake on an accepted
+// connection, selecting the first offered subprotocol found in subprotocols.
+func AcceptSocket(ctx context.Context, nc net.Conn, subprotocols []string) (FrameConn, error) {
+	c := newSocketConn(nc)
+
+	kind, payload, err := c.read(ctx)
+	if err == nil && kind != kindHandshake {
+		err = fmt.Errorf("unexpected frame kind %d", kind)
+	}
+	if err != nil {
+		_ = nc.Close()
+		return nil, fmt.Errorf("transport: read handshake: %w", err)
+	}
+
+	var offered []string
+	if len(payload) > 0 {
+		offered = strings.Split(string(payload), ",")
+	}
+	c.subprotocol = negotiate(offered, subprotocols)
+	if err := c.write(ctx, kindHandshake, []byte(c.subprotocol)); err != nil {
+		_ = nc.Close()
+		return nil, fmt.Errorf("transport: send handshake: %w", err)
+	}
+	return c, nil
+}
+
+type socketConn struct {
+	nc          net.Conn
+	br          *bufio.Reader
+	wmu         sync.Mutex
+	pongs       chan struct{}
+	subprotocol string
+	closed      atomic.Bool // Set by a graceful local or remote close
+}
+
+func newSocketConn(nc net.Conn) *socketConn {
+	return &socketConn{nc: nc, br: bufio.NewReader(nc), pongs: make(chan struct{}, 1)}
+}
+
+func (c *socketConn) ReadFrame(ctx context.Context) (MessageType, []byte, error) {
+	for {
+		kind, payload, err := c.read(ctx)
+		if err != nil {
+			if c.closed.Load() {
+				return 0, nil, fmt.Errorf("%w: %w", ErrClosed, err)
+			}
+			return 0, nil, err
+		}
+		switch kind {
+		case kindText:
+			return MessageText, payload, nil
+		case kindBinary:
+			return MessageBinary, payload, nil
+		case kindPing:
+			if err := c.write(ctx, kindPong, nil); err != nil {
+				return 0, nil, err
+			}
+		case kindPong:
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case kindClose:
+			c.closed.Store(true)
+			_ = c.nc.Close()
+			return 0, nil, ErrClosed
+		default:
+			_ = c.nc.Close()
+			return 0, nil, fmt.Errorf("transport: unexpected frame kind %d", kind)
+		}
+	}
+}
+
+func (c *socketConn) WriteFrame(ctx context.Context, typ MessageType, data []byte) error {
+	kind := kindText
+	if typ == MessageBinary {
+		kind = kindBinary
+	}
+	return c.write(ctx, kind, data)
+}
+
+// Ping sends a ping and waits for the pong observed by ReadFrame.
+func (c *socketConn) Ping(ctx context.Context) error {
+	// Discard a late pong from a previous ping
+	select {
+	case <-c.pongs:
+	default:
+	}
+	if err := c.write(ctx, kindPing, nil); err != nil {
+		return err
+	}
+	select {
+	case <-c.pongs:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *socketConn) Subprotocol() string { return c.subprotocol }
+
+func (c *socketConn) Close() error {
+	c.closed.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), socketCloseTimeout)
+	defer cancel()
+	writeErr := c.write(ctx, kindClose, nil)
+	if err := c.nc.Close(); err != nil {
+		return err
+	}
+	return writeErr
+}
+
+func (c *socketConn) CloseNow() error {
+	return c.nc.Close()
+}
+
+func (c *socketConn) read(ctx context.Context) (byte, []byte, error) {
+	stop := deadline(ctx, c.nc.SetReadDeadline)
+	defer stop()
+
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		return 0, nil, ioError(ctx, err)
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxSocketFrame {
+		return 0, nil, fmt.Errorf("transport: frame of %d bytes exceeds limit", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, ioError(ctx, err)
+	}
+	return hdr[0], payload, nil
+}
+
+func (c *socketConn) write(ctx context.Context, kind byte, payload []byte) error {
+	if len(payload) > maxSocketFrame {
+		return fmt.Errorf("transport: frame of %d bytes exceeds limit", len(payload))
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	stop := deadline(ctx, c.nc.SetWriteDeadline)
+	defer stop()
+
+	if _, err := c.nc.Write(appendFrame(nil, kind, payload)); err != nil {
+		return ioError(ctx, err)
+	}
+	return nil
+}
+
+// appendFrame appends a length-prefixed frame to buf.
+func appendFrame(buf []byte, kind byte, payload []byte) []byte {
+	buf = append(buf, kind)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)))
+	return append(buf, payload...)
+}
+
+// deadline applies the context deadline to a connection and interrupts
+// blocked I/O when ctx is cancelled. The returned func releases the watcher.
+func deadline(ctx context.Context, set func(time.Time) error) func() {
+	dl, _ := ctx.Deadline()
+	_ = set(dl)
+	stop := context.AfterFunc(ctx, func() { _ = set(aLongTimeAgo) })
+	return func() { stop() }
+}
+
+// ioError prefers the context error when I/O was interrupted by ctx.
+func ioError(ctx context.Context, err error) error {
+	if ctx.Err() != nil && isTimeout(err) {
+		return ctx.Err()
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,72 @@
+// Package transport abstracts the connection that carries WireChat frames.
+//
+// The client dials through a Transport and exchanges whole encoded frames over
+// the returned FrameConn. WebSocket is the default; Memory connects a client to
+// an in-process server for tests, and Socket speaks a length-prefixed framing
+// over TCP or Unix sockets for sidecar deployments.
+package transport
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by ReadFrame after the connection was closed normally
+// by either side. Any other read error means the connection was lost.
+var ErrClosed = errors.New("transport: connection closed")
+
+// MessageType distinguishes text frames from binary frames.
+type MessageType int
+
+const (
+	MessageText MessageType = iota + 1
+	MessageBinary
+)
+
+// String returns the string representation of a MessageType.
+func (t MessageType) String() string {
+	switch t {
+	case MessageText:
+		return "text"
+	case MessageBinary:
+		return "binary"
+	default:
+		return "unknown"
+	}
+}
+
+// Transport dials connections to a WireChat server.
+type Transport interface {
+	// Name identifies the transport in logs and state events.
+	Name() string
+	// Dial connects to url offering subprotocols in order of preference.
+	Dial(ctx context.Context, url string, subprotocols []string) (FrameConn, error)
+}
+
+// FrameConn exchanges whole frames. ReadFrame and WriteFrame may be called
+// concurrently with each other; Ping relies on a concurrent ReadFrame to
+// observe the reply.
+type FrameConn interface {
+	ReadFrame(ctx context.Context) (MessageType, []byte, error)
+	WriteFrame(ctx context.Context, typ MessageType, data []byte) error
+	// Ping checks that the peer is alive.
+	Ping(ctx context.Context) error
+	// Subprotocol returns the subprotocol selected by the server, or "" if none.
+	Subprotocol() string
+	// Close performs a graceful close handshake.
+	Close() error
+	// CloseNow closes the connection immediately; the peer observes a lost connection.
+	CloseNow() error
+}
+
+// negotiate picks the first offered subprotocol the server supports.
+func negotiate(offered, supported []string) string {
+	for _, p := range offered {
+		for _, s := range supported {
+			if p == s {
+				return p
+			}
+		}
+	}
+	return ""
+}
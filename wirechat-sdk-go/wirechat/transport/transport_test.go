@@ -0,0 +1,293 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSocketTransport(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	accepted := make(chan FrameConn, 1)
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn, err := AcceptSocket(ctx, nc, []string{"wirechat.json"})
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		// Echo frames until the client closes
+		for {
+			typ, data, err := conn.ReadFrame(ctx)
+			if err != nil {
+				return
+			}
+			if err := conn.WriteFrame(ctx, typ, data); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := Socket{}.Dial(ctx, "tcp://"+ln.Addr().String(), []string{"wirechat.cbor", "wirechat.json"})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	server := <-accepted
+	if client.Subprotocol() != "wirechat.json" || server.Subprotocol() != "wirechat.json" {
+		t.Fatalf("unexpected subprotocol %q/%q", client.Subprotocol(), server.Subprotocol())
+	}
+
+	// Ping replies are observed by a concurrent reader
+	type result struct {
+		typ  MessageType
+		data []byte
+		err  error
+	}
+	reads := make(chan result, 2)
+	go func() {
+		for {
+			typ, data, err := client.ReadFrame(ctx)
+			reads <- result{typ, data, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if err := client.WriteFrame(ctx, MessageBinary, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if r := <-reads; r.err != nil || r.typ != MessageBinary || string(r.data) != "\x01\x02\x03" {
+		t.Fatalf("unexpected echo %v %x %v", r.typ, r.data, r.err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if r := <-reads; !errors.Is(r.err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", r.err)
+	}
+}
+
+func TestLongPollFallback(t *testing.T) {
+	// The server only speaks long-polling, so the WebSocket upgrade fails
+	lp := NewLongPollServer("wirechat.json")
+	srv := httptest.NewServer(lp)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	tr := Fallback{WebSocket{}, LongPoll{}}
+	dialed := make(chan FrameConn, 1)
+	go func() {
+		conn, err := tr.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), []string{"wirechat.json"})
+		if err != nil {
+			t.Errorf("dial: %v", err)
+		}
+		dialed <- conn
+	}()
+
+	server, err := lp.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	client := <-dialed
+	if client == nil {
+		t.FailNow()
+	}
+	if name := ConnName(client, tr); name != "longpoll" {
+		t.Fatalf("expected longpoll, got %q", name)
+	}
+	if client.Subprotocol() != "wirechat.json" {
+		t.Fatalf("unexpected subprotocol %q", client.Subprotocol())
+	}
+
+	if err := client.WriteFrame(ctx, MessageText, []byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, data, err := server.ReadFrame(ctx); err != nil || string(data) != "ping" {
+		t.Fatalf("server read %q %v", data, err)
+	}
+	for _, msg := range []string{"a", "b"} {
+		if err := server.WriteFrame(ctx, MessageText, []byte(msg)); err != nil {
+			t.Fatalf("server write: %v", err)
+		}
+	}
+	for _, want := range []string{"a", "b"} {
+		if _, data, err := client.ReadFrame(ctx); err != nil || string(data) != want {
+			t.Fatalf("client read %q %v, want %q", data, err, want)
+		}
+	}
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, _, err := server.ReadFrame(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestLongPollLostResponse(t *testing.T) {
+	lp := NewLongPollServer()
+	// The first batch never reaches the client, as if a proxy dropped it
+	var dropped atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && !dropped.Load() {
+			rec := httptest.NewRecorder()
+			lp.ServeHTTP(rec, r)
+			if rec.Code == http.StatusOK {
+				dropped.Store(true)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(rec.Code)
+			return
+		}
+		lp.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dialed := make(chan FrameConn, 1)
+	go func() {
+		conn, err := LongPoll{}.Dial(ctx, srv.URL, nil)
+		if err != nil {
+			t.Errorf("dial: %v", err)
+		}
+		dialed <- conn
+	}()
+	server, err := lp.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	client := <-dialed
+	if client == nil {
+		t.FailNow()
+	}
+	defer client.Close()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		if err := server.WriteFrame(ctx, MessageText, []byte(msg)); err != nil {
+			t.Fatalf("server write: %v", err)
+		}
+	}
+	var got []string
+	for len(got) < 3 {
+		_, data, err := client.ReadFrame(ctx)
+		if err != nil {
+			t.Fatalf("client read after %q: %v", got, err)
+		}
+		got = append(got, string(data))
+	}
+	if !dropped.Load() || strings.Join(got, "") != "abc" {
+		t.Fatalf("got %q (dropped %v), want a, b, c once each", got, dropped.Load())
+	}
+}
+
+// sizeRecorder counts the response body bytes written through it.
+type sizeRecorder struct {
+	http.ResponseWriter
+	n int
+}
+
+func (w *sizeRecorder) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func TestLongPollBatchLimit(t *testing.T) {
+	lp := NewLongPollServer()
+	var largest atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &sizeRecorder{ResponseWriter: w}
+		lp.ServeHTTP(rec, r)
+		for n := int64(rec.n); ; {
+			cur := largest.Load()
+			if n <= cur || largest.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dialed := make(chan FrameConn, 1)
+	go func() {
+		conn, err := LongPoll{}.Dial(ctx, srv.URL, nil)
+		if err != nil {
+			t.Errorf("dial: %v", err)
+		}
+		dialed <- conn
+	}()
+	server, err := lp.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	client := <-dialed
+	if client == nil {
+		t.FailNow()
+	}
+	defer client.Close()
+
+	// Any two frames together exceed the batch size
+	frames := make([][]byte, 3)
+	for i := range frames {
+		frames[i] = bytes.Repeat([]byte{byte('a' + i)}, pollBatchSize/2+1)
+		if err := server.WriteFrame(ctx, MessageBinary, frames[i]); err != nil {
+			t.Fatalf("server write: %v", err)
+		}
+	}
+	for i := range frames {
+		_, data, err := client.ReadFrame(ctx)
+		if err != nil {
+			t.Fatalf("client read %d: %v", i, err)
+		}
+		if !bytes.Equal(data, frames[i]) {
+			t.Fatalf("frame %d out of order or corrupted", i)
+		}
+	}
+	if largest.Load() > pollBatchSize {
+		t.Fatalf("batch of %d bytes exceeds %d", largest.Load(), pollBatchSize)
+	}
+}
+
+func TestLongPollResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, maxPollResponse+1))
+	}))
+	defer srv.Close()
+
+	c := &pollConn{client: srv.Client()}
+	if _, _, _, err := c.do(context.Background(), http.MethodGet, srv.URL, "", nil); !errors.Is(err, errPollTooLarge) {
+		t.Fatalf("expected errPollTooLarge, got %v", err)
+	}
+}
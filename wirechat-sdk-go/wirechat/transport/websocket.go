@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/coder/websocket"
+)
+
+// WebSocket dials ws:// and wss:// URLs.
+type WebSocket struct {
+	HTTPClient *http.Client // Optional client used for the handshake
+	Header     http.Header  // Optional extra handshake headers
+}
+
+// Name returns "websocket".
+func (WebSocket) Name() string { return "websocket" }
+
+// Dial performs the WebSocket handshake.
+func (t WebSocket) Dial(ctx context.Context, url string, subprotocols []string) (FrameConn, error) {
+	ws, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPClient:   t.HTTPClient,
+		HTTPHeader:   t.Header,
+		Subprotocols: subprotocols,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewWebSocketConn(ws), nil
+}
+
+// NewWebSocketConn wraps an established WebSocket, e.g. one accepted by a server.
+func NewWebSocketConn(ws *websocket.Conn) FrameConn {
+	return &wsConn{ws: ws}
+}
+
+type wsConn struct {
+	ws *websocket.Conn
+}
+
+func (c *wsConn) ReadFrame(ctx context.Context) (MessageType, []byte, error) {
+	typ, data, err := c.ws.Read(ctx)
+	if err != nil {
+		if websocket.CloseStatus(err) == websocket.StatusNormalClosure {
+			return 0, nil, fmt.Errorf("%w: %w", ErrClosed, err)
+		}
+		return 0, nil, err
+	}
+	if typ == websocket.MessageBinary {
+		return MessageBinary, data, nil
+	}
+	return MessageText, data, nil
+}
+
+func (c *wsConn) WriteFrame(ctx context.Context, typ MessageType, data []byte) error {
+	wsType := websocket.MessageText
+	if typ == MessageBinary {
+		wsType = websocket.MessageBinary
+	}
+	return c.ws.Write(ctx, wsType, data)
+}
+
+func (c *wsConn) Ping(ctx context.Context) error { return c.ws.Ping(ctx) }
+func (c *wsConn) Subprotocol() string            { return c.ws.Subprotocol() }
+func (c *wsConn) Close() error                   { return c.ws.Close(websocket.StatusNormalClosure, "") }
+func (c *wsConn) CloseNow() error                { return c.ws.CloseNow() }
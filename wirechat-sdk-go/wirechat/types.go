@@ -1,6 +1,10 @@
 package wirechat
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/codec"
+)
 
 const (
 	ProtocolVersion = 1
@@ -25,7 +29,8 @@ type Inbound struct {
 	Data any    `json:"data,omitempty"`
 }
 
-// Outbound is the envelope server -> client.
+// Outbound is the envelope server -> client. Data holds JSON with every
+// codec; binary codecs convert it when the frame is decoded.
 type Outbound struct {
 	Type  string          `json:"type"`
 	Event string          `json:"event,omitempty"`
@@ -64,7 +69,9 @@ func (e *Error) Error() string {
 	return e.Code + ": " + e.Msg
 }
 
-// UnmarshalData decodes RawMessage into target.
+// UnmarshalData decodes RawMessage, such as Outbound.Data, into target. Every
+// codec delivers RawMessage as JSON, so it works the same whatever codec the
+// frame was received with.
 func UnmarshalData(data json.RawMessage, v any) error {
-	return json.Unmarshal(data, v)
+	return codec.JSON.Unmarshal(data, v)
 }
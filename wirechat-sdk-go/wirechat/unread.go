@@ -0,0 +1,303 @@
+package wirechat
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// unreadFetchLimit caps how many messages FetchUnread pages through, so a
+// room that was never read does not download its whole history.
+const unreadFetchLimit = 1000
+
+// UnreadTracker counts incoming messages per room since the last-read marker.
+// Messages from the client's own user are not counted. Markers are persisted
+// through a ReadStore so counts survive restarts.
+//
+// A room without a marker starts counting when the tracker first sees it:
+// the messages in its join history, or the newest message found by
+// FetchUnread, become the saved marker instead of counting as unread.
+type UnreadTracker struct {
+	client *Client
+	store  ReadStore
+
+	mu       sync.Mutex
+	user     string
+	rooms    map[string]*unreadRoom
+	onChange func(room string, count int)
+}
+
+// unreadRoom is the bookkeeping for a single room.
+type unreadRoom struct {
+	marker int64   // Last message ID marked as read
+	seen   int64   // Highest message ID observed
+	unread []int64 // IDs of unread messages in arrival order (0 for guest messages)
+}
+
+// NewUnreadTracker attaches a tracker to c and loads saved markers from store.
+// A nil store keeps markers in memory. Create the tracker before Connect.
+// Own messages are recognized by Config.User; use SetUser after a JWT login.
+func NewUnreadTracker(c *Client, store ReadStore) (*UnreadTracker, error) {
+	if store == nil {
+		store = NewMemoryReadStore()
+	}
+	markers, err := store.Load()
+	if err != nil {
+		return nil, WrapError(ErrorInvalidConfig, "load read markers", err)
+	}
+
+	t := &UnreadTracker{
+		client: c,
+		store:  store,
+		user:   c.config().User,
+		rooms:  make(map[string]*unreadRoom, len(markers)),
+	}
+	for room, id := range markers {
+		t.rooms[room] = &unreadRoom{marker: id, seen: id}
+	}
+	c.dispatcher.addHooks(eventHooks{
+		message: func(ev MessageEvent) { t.observe(ev.Room, []MessageEvent{ev}, true) },
+		history: func(ev HistoryEvent) { t.observe(ev.Room, ev.Messages, false) },
+	})
+	return t, nil
+}
+
+// SetUser sets the user name whose messages are not counted.
+func (t *UnreadTracker) SetUser(user string) {
+	t.mu.Lock()
+	t.user = user
+	t.mu.Unlock()
+}
+
+// OnChange registers a callback fired whenever the unread count of a room changes.
+func (t *UnreadTracker) OnChange(fn func(room string, count int)) {
+	t.mu.Lock()
+	t.onChange = fn
+	t.mu.Unlock()
+}
+
+// roomLocked returns the bookkeeping for room, creating it on first use.
+func (t *UnreadTracker) roomLocked(room string) *unreadRoom {
+	r, ok := t.rooms[room]
+	if !ok {
+		r = &unreadRoom{}
+		t.rooms[room] = r
+	}
+	return r
+}
+
+// observe counts new messages. History replays are deduplicated by ID, so
+// only live guest messages (ID 0) are counted. The first history of a room
+// without a marker sets its baseline.
+func (t *UnreadTracker) observe(room string, msgs []MessageEvent, live bool) {
+	msgs = slices.Clone(msgs)
+	slices.SortStableFunc(msgs, func(a, b MessageEvent) int { return cmp.Compare(a.ID, b.ID) })
+
+	t.mu.Lock()
+	r := t.roomLocked(room)
+	baseline := !live && r.marker == 0 && r.seen == 0
+	before := len(r.unread)
+	for _, ev := range msgs {
+		if ev.ID == 0 && !live {
+			continue
+		}
+		if ev.ID != 0 {
+			if ev.ID <= r.seen {
+				continue
+			}
+			r.seen = ev.ID
+		}
+		if ev.User != t.user && !baseline {
+			r.unread = append(r.unread, ev.ID)
+		}
+	}
+	seen, count, fn := r.seen, len(r.unread), t.onChange
+	t.mu.Unlock()
+
+	if fn != nil && count != before {
+		fn(room, count)
+	}
+	if baseline {
+		if err := t.setBaseline(room, seen); err != nil {
+			t.client.logger.Warn("failed to save read marker", map[string]any{"room": room, "error": err.Error()})
+		}
+	}
+}
+
+// setBaseline makes id the marker of a room that has none yet and saves it.
+func (t *UnreadTracker) setBaseline(room string, id int64) error {
+	t.mu.Lock()
+	r := t.roomLocked(room)
+	if r.marker != 0 || id == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	r.marker = id
+	r.seen = max(r.seen, id)
+	t.mu.Unlock()
+
+	if err := t.store.Save(room, id); err != nil {
+		return WrapError(ErrorUnknown, "save read marker", err)
+	}
+	return nil
+}
+
+// Unread returns the number of unread messages in room.
+func (t *UnreadTracker) Unread(room string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r, ok := t.rooms[room]; ok {
+		return len(r.unread)
+	}
+	return 0
+}
+
+// UnreadAll returns the unread counts of every room that has unread messages.
+func (t *UnreadTracker) UnreadAll() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[string]int)
+	for room, r := range t.rooms {
+		if len(r.unread) > 0 {
+			counts[room] = len(r.unread)
+		}
+	}
+	return counts
+}
+
+// LastRead returns the ID of the last message marked as read in room.
+func (t *UnreadTracker) LastRead(room string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r, ok := t.rooms[room]; ok {
+		return r.marker
+	}
+	return 0
+}
+
+// MarkRead advances the last-read marker of room to messageID and saves it.
+// A messageID of 0, or one at or past the newest message, marks everything
+// seen so far as read. The marker never moves backwards.
+func (t *UnreadTracker) MarkRead(room string, messageID int64) error {
+	t.mu.Lock()
+	r := t.roomLocked(room)
+	before := len(r.unread)
+	if messageID == 0 || messageID >= r.seen {
+		messageID = max(messageID, r.seen)
+		r.unread = nil
+	} else {
+		r.unread = slices.DeleteFunc(r.unread, func(id int64) bool { return id != 0 && id <= messageID })
+	}
+	changed := messageID > r.marker
+	if changed {
+		r.marker = messageID
+	}
+	marker, count, fn := r.marker, len(r.unread), t.onChange
+	t.mu.Unlock()
+
+	if fn != nil && count != before {
+		fn(room, count)
+	}
+	if !changed {
+		return nil
+	}
+	if err := t.store.Save(room, marker); err != nil {
+		return WrapError(ErrorUnknown, "save read marker", err)
+	}
+	return nil
+}
+
+// FetchUnread computes the unread count of a room the client has not joined
+// from REST history, paging back by message ID until the last-read marker.
+// Counts are capped at 1000. Fetched messages are merged into the messages
+// already counted, so later live messages add to the result. For a room
+// that has neither a marker nor observed messages the newest message becomes
+// the baseline and the count is 0; a room without a marker whose messages the
+// tracker already counts is only paged back to the newest observed message.
+// Pass room "" to resolve the name from roomID, or roomID 0 to resolve the
+// ID from the name.
+func (t *UnreadTracker) FetchUnread(ctx context.Context, room string, roomID int64) (int, error) {
+	api := t.client.RESTAPI()
+	if api == nil {
+		return 0, NewError(ErrorInvalidConfig, "REST client not configured")
+	}
+	room, roomID, err := t.client.resolveRoom(ctx, room, roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	r := t.roomLocked(room)
+	// Without a marker everything up to seen is already counted live
+	stop, user, fresh := r.marker, t.user, r.marker == 0 && r.seen == 0
+	if stop == 0 {
+		stop = r.seen
+	}
+	t.mu.Unlock()
+
+	if fresh {
+		page, err := api.GetMessages(ctx, roomID, 1, nil)
+		if err != nil {
+			return 0, err
+		}
+		var newest int64
+		for _, m := range page.Messages {
+			newest = max(newest, m.ID)
+		}
+		return 0, t.setBaseline(room, newest)
+	}
+
+	// Pages may come in any order: page back from the oldest ID of each
+	// page and skip what is already read instead of stopping early.
+	var unread []int64
+	var newest int64
+	var before *int64
+	for fetched := 0; fetched < unreadFetchLimit; {
+		page, err := api.GetMessages(ctx, roomID, min(100, unreadFetchLimit-fetched), before)
+		if err != nil {
+			return 0, err
+		}
+		var oldest int64
+		reached := false
+		for _, m := range page.Messages {
+			if oldest == 0 || m.ID < oldest {
+				oldest = m.ID
+			}
+			if m.ID <= stop {
+				reached = true
+				continue
+			}
+			newest = max(newest, m.ID)
+			if m.User != user {
+				unread = append(unread, m.ID)
+			}
+		}
+		fetched += len(page.Messages)
+		if reached || !page.HasMore || len(page.Messages) == 0 {
+			break
+		}
+		before = &oldest
+	}
+	slices.Sort(unread)
+
+	t.mu.Lock()
+	r = t.roomLocked(room)
+	prev := len(r.unread)
+	if newest > r.seen {
+		r.seen = newest
+	}
+	// Live messages may have arrived and MarkRead may have run meanwhile
+	for _, id := range unread {
+		if id > r.marker && !slices.Contains(r.unread, id) {
+			r.unread = append(r.unread, id)
+		}
+	}
+	count, fn := len(r.unread), t.onChange
+	t.mu.Unlock()
+
+	if fn != nil && count != prev {
+		fn(room, count)
+	}
+	return count, nil
+}
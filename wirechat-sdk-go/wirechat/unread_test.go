@@ -0,0 +1,130 @@
+package wirechat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestUnreadBaseline(t *testing.T) {
+	// 250 messages served oldest first, so page order says nothing about IDs
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		before, err := strconv.Atoi(r.URL.Query().Get("before"))
+		if err != nil {
+			before = 251
+		}
+		first := max(1, before-limit)
+		var page []map[string]any
+		for id := first; id < before; id++ {
+			page = append(page, map[string]any{"id": id, "user": "bob"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"messages": page, "has_more": first > 1})
+	}))
+	defer api.Close()
+
+	cfg := DefaultConfig()
+	cfg.User = "me"
+	cfg.RESTBaseURL = api.URL
+	c := NewClient(&cfg)
+	tracker, err := NewUnreadTracker(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Join history of a room without a marker is the baseline
+	raw, _ := json.Marshal(HistoryEvent{Room: "general", Messages: []MessageEvent{{ID: 4, User: "bob"}, {ID: 5, User: "bob"}}})
+	c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: eventHistory, Data: raw})
+	raw, _ = json.Marshal(MessageEvent{ID: 6, Room: "general", User: "bob"})
+	c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: eventMessage, Data: raw})
+	if n, last := tracker.Unread("general"), tracker.LastRead("general"); n != 1 || last != 5 {
+		t.Fatalf("general: %d unread, marker %d; want 1 unread after marker 5", n, last)
+	}
+
+	// So is the newest message FetchUnread finds
+	if n, err := tracker.FetchUnread(ctx, "random", 7); err != nil || n != 0 || tracker.LastRead("random") != 250 {
+		t.Fatalf("random: %d unread (%v), marker %d", n, err, tracker.LastRead("random"))
+	}
+
+	// Live messages of a room without a marker are counted already: only
+	// newer messages are fetched and merged with them
+	for _, id := range []int64{248, 249} {
+		raw, _ = json.Marshal(MessageEvent{ID: id, Room: "live", User: "bob"})
+		c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: eventMessage, Data: raw})
+	}
+	if n, err := tracker.FetchUnread(ctx, "live", 7); err != nil || n != 3 || tracker.Unread("live") != 3 {
+		t.Fatalf("live: %d unread (%v), want 3", n, err)
+	}
+
+	// With a marker, pages are followed by their oldest ID
+	if err := tracker.MarkRead("paged", 50); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := tracker.FetchUnread(ctx, "paged", 7); err != nil || n != 200 {
+		t.Fatalf("paged: %d unread (%v), want 200", n, err)
+	}
+}
+
+func TestUnreadTracker(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rooms/7/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{
+			{"id": 6, "user": "bob"}, {"id": 5, "user": "me"}, {"id": 4, "user": "bob"}, {"id": 3, "user": "bob"},
+		}})
+	}))
+	defer api.Close()
+
+	cfg := DefaultConfig()
+	cfg.User = "me"
+	cfg.RESTBaseURL = api.URL
+	c := NewClient(&cfg)
+	path := t.TempDir() + "/read.json"
+	tracker, err := NewUnreadTracker(c, NewFileReadStore(path))
+	if err != nil {
+		t.Fatalf("tracker: %v", err)
+	}
+	var changes int
+	tracker.OnChange(func(string, int) { changes++ })
+
+	dispatch := func(event string, v any) {
+		raw, _ := json.Marshal(v)
+		c.dispatcher.Dispatch(Outbound{Type: outboundEvent, Event: event, Data: raw})
+	}
+	dispatch(eventMessage, MessageEvent{ID: 1, Room: "general", User: "bob"})
+	dispatch(eventMessage, MessageEvent{ID: 2, Room: "general", User: "me"})
+	dispatch(eventMessage, MessageEvent{ID: 3, Room: "general", User: "bob"})
+	// A history replay only adds messages not seen yet
+	dispatch(eventHistory, HistoryEvent{Room: "general", Messages: []MessageEvent{
+		{ID: 4, Room: "general", User: "bob"}, {ID: 3, Room: "general", User: "bob"},
+	}})
+	if n := tracker.Unread("general"); n != 3 {
+		t.Fatalf("expected 3 unread, got %d", n)
+	}
+
+	if err := tracker.MarkRead("general", 3); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+	if n := tracker.Unread("general"); n != 1 || changes != 4 {
+		t.Fatalf("expected 1 unread after 4 changes, got %d after %d", n, changes)
+	}
+
+	// Markers survive a restart through the file store
+	tracker, err = NewUnreadTracker(NewClient(&cfg), NewFileReadStore(path))
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if tracker.LastRead("general") != 3 {
+		t.Fatalf("marker not persisted: %d", tracker.LastRead("general"))
+	}
+	n, err := tracker.FetchUnread(context.Background(), "general", 7)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 unread from REST, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,240 @@
+package wirechat
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+// fixedFields are read once by NewClient and cannot be changed by UpdateConfig.
+var fixedFields = []string{
+	"WriteQueueSize", "WriteFairness", "LaneWeights",
+	"BreakerThreshold", "BreakerCooldown", "BreakerMaxCooldown",
+	"Metrics", "Tracer", "TrackRoster", "MessageStore",
+}
+
+// Config returns a copy of the current configuration.
+func (c *Client) Config() Config {
+	cfg := *c.config()
+	cfg.Endpoints = slices.Clone(cfg.Endpoints)
+	cfg.LaneWeights = maps.Clone(cfg.LaneWeights)
+	return cfg
+}
+
+// UpdateConfig changes the configuration of a running client without losing
+// joined rooms or registered handlers. fn edits a copy of the current config;
+// the result is validated and applied only if it is valid.
+//
+// Changes to URL, Endpoints, Token, User, Protocol, Codec or Transport
+// gracefully reconnect an active connection: the old connection is closed, a
+// new one is dialed, hello is re-sent, rooms are re-joined and buffered
+// messages are replayed. Enable BufferMessages to keep Send calls made during
+// the switch. If the new connection fails, the regular auto-reconnect loop
+// takes over when AutoReconnect is enabled, and the error is returned.
+//
+// Reconnect, resend and buffer settings apply immediately. Timeouts and
+// heartbeat settings apply from the next connection. The REST client always
+// uses the same token and base URL as the WebSocket connection; they are
+// updated in place on the existing REST client. Write queue, breaker,
+// Metrics and Tracer settings are fixed by NewClient and changing them is an
+// error.
+func (c *Client) UpdateConfig(fn func(*Config)) error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	old := c.config()
+	next := c.Config()
+	fn(&next)
+	normalizeConfig(&next)
+
+	if err := checkFixedFields(old, &next); err != nil {
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	c.cfg.Store(&next)
+
+	if next.URL != old.URL || next.RESTBaseURL != old.RESTBaseURL || !slices.Equal(next.Endpoints, old.Endpoints) ||
+		next.EndpointMaxFailures != old.EndpointMaxFailures || next.EndpointBanDuration != old.EndpointBanDuration {
+		c.endpoints.reset(&next)
+	}
+
+	// Keep REST on the same token and base URL as the WebSocket connection
+	if restBaseURL := next.restBaseURL(); restBaseURL != old.restBaseURL() {
+		c.REST.SetBaseURL(restBaseURL)
+	}
+	c.REST.SetToken(next.Token)
+	// Another server or user may see different rooms
+	if next.restBaseURL() != old.restBaseURL() || next.Token != old.Token {
+		c.rooms.invalidate()
+	}
+
+	if !needsReconnect(old, &next) {
+		return nil
+	}
+	return c.restart()
+}
+
+// checkFixedFields reports every field that NewClient has already consumed.
+func checkFixedFields(old, next *Config) error {
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem()
+	var errs []error
+	for _, name := range fixedFields {
+		if !reflect.DeepEqual(ov.FieldByName(name).Interface(), nv.FieldByName(name).Interface()) {
+			errs = append(errs, NewError(ErrorInvalidConfig, name+": cannot be changed after NewClient"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// needsReconnect reports whether the change affects the dial or the hello handshake.
+func needsReconnect(old, next *Config) bool {
+	return next.URL != old.URL ||
+		!slices.Equal(next.Endpoints, old.Endpoints) ||
+		next.Token != old.Token ||
+		next.User != old.User ||
+		next.Protocol != old.Protocol ||
+		next.Codec.Name() != old.Codec.Name() ||
+		!sameTransport(next.Transport, old.Transport)
+}
+
+// sameTransport compares transports by identity rather than by deep equality:
+// pointers, maps and channels must refer to the same object, while structs,
+// slices and other values are compared element by element. A transport with a
+// func field only matches when both funcs are nil.
+func sameTransport(a, b transport.Transport) bool {
+	return sameValue(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func sameValue(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Func:
+		return a.IsNil() && b.IsNil()
+	case reflect.Interface:
+		return sameValue(a.Elem(), b.Elem())
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !sameValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !sameValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
+
+// restart replaces the active connection with one that uses the current
+// config. It does nothing while disconnected, since the next connection
+// attempt picks up the new config anyway.
+func (c *Client) restart() error {
+	c.mu.Lock()
+	if !c.connected {
+		c.mu.Unlock()
+		return nil
+	}
+	c.connected = false
+	cancel, conn := c.cancel, c.conn
+	c.readDone = nil // Detach the old read loop so it leaves the state alone
+	// The new run is installed before dialing, so Close aborts the dial
+	ctx, runCancel := context.WithCancel(context.Background())
+	c.cancel = runCancel
+	c.mu.Unlock()
+
+	c.logger.Info("reconnecting to apply config", nil)
+	cancel()
+	_ = conn.Close()
+	c.resetResend(NewError(ErrorDisconnected, "connection replaced before resend"))
+	c.setState(StateReconnecting, nil)
+
+	ep, err := c.dialEndpoint(ctx)
+	if err == nil {
+		err = c.hello(ctx, ep)
+	}
+	if err != nil {
+		if c.isClosed() {
+			return c.abortRestart(runCancel)
+		}
+		c.setState(StateDisconnected, err)
+		if !c.config().AutoReconnect {
+			runCancel()
+			c.setState(StateError, err)
+			return err
+		}
+		done := make(chan struct{})
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return c.abortRestart(runCancel)
+		}
+		c.readDone = done
+		c.mu.Unlock()
+		go c.recover(ctx, done)
+		return err
+	}
+
+	if !c.start(ctx, runCancel) {
+		_ = c.currentConn().Close()
+		return c.abortRestart(runCancel)
+	}
+	c.setState(StateConnected, nil)
+	c.logger.Info("reconnected", map[string]any{"url": ep.URL, "reason": "config update"})
+	c.restore(ctx)
+	return nil
+}
+
+// abortRestart stops a restart that raced with Close and restores the closed state.
+func (c *Client) abortRestart(cancel context.CancelFunc) error {
+	cancel()
+	if c.State() != StateClosed {
+		c.setState(StateClosed, nil)
+	}
+	return NewError(ErrorDisconnected, "client closed during reconnect")
+}
+
+// isClosed reports whether Close has been called since the last Connect.
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// recover runs the auto-reconnect loop after a failed restart and then
+// continues as the read loop of the new connection.
+func (c *Client) recover(ctx context.Context, done chan struct{}) {
+	if c.reconnectLoop(ctx) {
+		c.readLoop(ctx, done)
+		return
+	}
+	close(done)
+}
+
+// isCurrentRun reports whether done belongs to the active read loop.
+func (c *Client) isCurrentRun(done chan struct{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readDone == done
+}
@@ -0,0 +1,197 @@
+package wirechat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/rest"
+	"github.com/vovakirdan/wirechat-sdk/wirechat-sdk-go/wirechat/transport"
+)
+
+func TestUpdateConfigREST(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.URL = "ws://localhost:8080/ws"
+	c := NewClient(&cfg)
+	rc := c.REST
+
+	if c.RESTAPI() != nil {
+		t.Fatal("expected no REST API without a base URL")
+	}
+	if _, err := c.REST.ListRooms(context.Background()); !errors.Is(err, rest.ErrNoBaseURL) {
+		t.Fatalf("expected ErrNoBaseURL, got %v", err)
+	}
+
+	// UpdateConfig configures the existing REST client while others read it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for c.RESTAPI() == nil {
+		}
+	}()
+	if err := c.UpdateConfig(func(cfg *Config) {
+		cfg.RESTBaseURL = "http://localhost:8080/api"
+		cfg.Token = "token"
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	<-done
+	if c.REST != rc || c.RESTAPI() != rest.API(rc) {
+		t.Fatal("REST and RESTAPI must share one client")
+	}
+	if c.REST.BaseURL() != "http://localhost:8080/api" || c.REST.Token() != "token" {
+		t.Fatalf("REST not updated: %s %q", c.REST.BaseURL(), c.REST.Token())
+	}
+}
+
+func TestSameTransport(t *testing.T) {
+	header := http.Header{"X-Test": {"1"}}
+	lp := transport.LongPoll{Header: header, Path: "/poll"}
+	mem := transport.NewMemory()
+	cases := []struct {
+		a, b transport.Transport
+		same bool
+	}{
+		{transport.WebSocket{}, transport.WebSocket{}, true},
+		{lp, transport.LongPoll{Header: header, Path: "/poll"}, true},
+		{lp, transport.LongPoll{Header: http.Header{"X-Test": {"1"}}, Path: "/poll"}, false},
+		{lp, transport.LongPoll{Header: header, Path: "/other"}, false},
+		{transport.Fallback{transport.WebSocket{}, lp}, transport.Fallback{transport.WebSocket{}, lp}, true},
+		{transport.Fallback{transport.WebSocket{}}, transport.Fallback{lp}, false},
+		{mem, mem, true},
+		{mem, transport.NewMemory(), false},
+		{transport.WebSocket{}, nil, false},
+	}
+	for i, tc := range cases {
+		if got := sameTransport(tc.a, tc.b); got != tc.same {
+			t.Errorf("case %d: sameTransport = %v, want %v", i, got, tc.same)
+		}
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	mem := transport.NewMemory()
+	cfg := DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.RESTBaseURL = "http://localhost:8080/api"
+	cfg.Transport = mem
+	cfg.Token = "old-token"
+	c := NewClient(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	accept := func(token string) transport.FrameConn {
+		t.Helper()
+		conn, err := mem.Accept(ctx)
+		if err != nil {
+			t.Fatalf("accept: %v", err)
+		}
+		if _, data, err := conn.ReadFrame(ctx); err != nil || !bytes.Contains(data, []byte(token)) {
+			t.Fatalf("expected hello with %s, got %s (%v)", token, data, err)
+		}
+		return conn
+	}
+
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	first := accept("old-token")
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	if err := c.Join(ctx, "general"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if _, _, err := first.ReadFrame(ctx); err != nil {
+		t.Fatalf("read join: %v", err)
+	}
+
+	// Settings fixed by NewClient and invalid values are rejected as a whole
+	err := c.UpdateConfig(func(cfg *Config) {
+		cfg.Token = "ignored"
+		cfg.WriteQueueSize = 64
+	})
+	if !errors.Is(err, NewError(ErrorInvalidConfig, "")) || c.Config().Token != "old-token" {
+		t.Fatalf("expected fixed field rejection, got %v", err)
+	}
+	if err := c.UpdateConfig(func(cfg *Config) { cfg.URL = "" }); err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	// Rotating the token reconnects with a new hello and rejoins the room
+	updated := make(chan error, 1)
+	go func() { updated <- c.UpdateConfig(func(cfg *Config) { cfg.Token = "new-token" }) }()
+	second := accept("new-token")
+	if err := <-updated; err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, data, err := second.ReadFrame(ctx); err != nil || !bytes.Contains(data, []byte(`"general"`)) {
+		t.Fatalf("expected rejoin, got %s (%v)", data, err)
+	}
+	if c.REST.Token() != "new-token" {
+		t.Fatalf("REST token not rotated: %q", c.REST.Token())
+	}
+	if c.State() != StateConnected {
+		t.Fatalf("expected connected, got %v", c.State())
+	}
+
+	// Settings outside the handshake apply without reconnecting
+	if err := c.UpdateConfig(func(cfg *Config) { cfg.MaxBufferSize = 10 }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if c.Config().MaxBufferSize != 10 || c.State() != StateConnected {
+		t.Fatal("buffer size not applied")
+	}
+}
+
+func TestUpdateConfigClose(t *testing.T) {
+	mem := transport.NewMemory()
+	cfg := DefaultConfig()
+	cfg.URL = "memory://test"
+	cfg.Transport = mem
+	cfg.AutoReconnect = true
+	c := NewClient(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	connected := make(chan error, 1)
+	go func() { connected <- c.Connect(ctx) }()
+	if _, err := mem.Accept(ctx); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if err := <-connected; err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	// Nobody accepts the new connection, so the restart blocks in dial
+	updated := make(chan error, 1)
+	go func() { updated <- c.UpdateConfig(func(cfg *Config) { cfg.Token = "new-token" }) }()
+	for c.State() != StateReconnecting {
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Close aborts the dial, and the restart leaves the client closed
+	select {
+	case err := <-updated:
+		if !errors.Is(err, NewError(ErrorDisconnected, "")) {
+			t.Fatalf("expected disconnected error, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("restart not aborted by Close")
+	}
+	if c.State() != StateClosed {
+		t.Fatalf("expected closed, got %v", c.State())
+	}
+	acceptCtx, acceptCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer acceptCancel()
+	if conn, err := mem.Accept(acceptCtx); err == nil {
+		t.Fatalf("unexpected dial after Close: %v", conn)
+	}
+}